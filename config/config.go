@@ -3,18 +3,33 @@ package config
 import (
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 type (
 	Config struct {
-		Server *Server
-		Db     *Db
+		Server      *Server
+		Db          *Db
+		Pricing     *Pricing
+		Idempotency *Idempotency
+		OrderPolicy *OrderPolicy
+		Tax         *Tax
+		Shipping    *Shipping
+		Payments    *Payments
+		Webhooks    *Webhooks
+		Outbox      *Outbox
 	}
 
 	Server struct {
 		Port int
+		// RequestTimeout is the deadline of a request, after which its
+		// queries are cancelled and it is answered 504 Gateway Timeout
+		RequestTimeout time.Duration
+		// RouteTimeouts replace RequestTimeout for the routes they name as
+		// "METHOD /path", e.g. "POST /api/orders"
+		RouteTimeouts map[string]time.Duration
 	}
 
 	Db struct {
@@ -25,6 +40,144 @@ type (
 		DBName   string
 		SSLMode  string
 		TimeZone string
+		// MaxOpenConns and MaxIdleConns bound the connection pool, 0 leaves
+		// open connections unlimited
+		MaxOpenConns int
+		MaxIdleConns int
+		// ConnMaxLifetime and ConnMaxIdleTime recycle connections that are
+		// old or unused, 0 keeps them
+		ConnMaxLifetime time.Duration
+		ConnMaxIdleTime time.Duration
+		// StatementTimeout makes the server abort longer statements, 0 lets
+		// them run
+		StatementTimeout time.Duration
+		// ConnectAttempts to reach the database at startup, waiting
+		// ConnectRetryDelay after the first failure and twice as long after
+		// every further one
+		ConnectAttempts   int
+		ConnectRetryDelay time.Duration
+	}
+
+	Pricing struct {
+		// DefaultCurrency is the ISO 4217 code assumed for prices stored
+		// before amounts carried their own currency
+		DefaultCurrency string
+	}
+
+	OrderLimits struct {
+		// MaxOpenOrders is how many orders a customer may have open at
+		// once, 0 for no limit
+		MaxOpenOrders *int
+		// MaxOrderValue caps the total of an order, in minor units, per
+		// currency code. Currencies not listed are not capped
+		MaxOrderValue map[string]int64
+	}
+
+	// OrderPolicy limits apply to every customer unless the customer's
+	// country or the customer's own override sets a different limit
+	OrderPolicy struct {
+		OrderLimits `mapstructure:",squash"`
+		Countries   map[string]OrderLimits
+	}
+
+	Tax struct {
+		// PricesIncludeTax means product prices already contain tax, so
+		// tax is reported on orders but not added to their total
+		PricesIncludeTax bool
+		Countries        map[string]TaxCountry
+	}
+
+	// TaxCountry holds the tax rates of one country, in percent. Products of
+	// a category listed in Categories are taxed at that rate instead
+	TaxCountry struct {
+		Name       string
+		Rate       float64
+		Categories map[string]float64
+	}
+
+	// Shipping is priced by the zone of the shipping address country and
+	// the weight of the order. Without zones shipping is free
+	Shipping struct {
+		Zones []ShippingZone
+	}
+
+	// ShippingZone prices shipping to a group of countries. The country "*"
+	// matches every country not listed in another zone
+	ShippingZone struct {
+		Name      string
+		Countries []string
+		Currency  string
+		// Rates are weight brackets, an order pays the price of the
+		// lightest bracket its weight fits in
+		Rates []ShippingRate
+		// ExtraPerKg is charged, in minor units, for every started
+		// kilogram above the heaviest bracket; 0 refuses heavier orders
+		ExtraPerKg int64
+	}
+
+	ShippingRate struct {
+		MaxWeightGrams int
+		// Price in minor units of the zone currency
+		Price int64
+	}
+
+	Payments struct {
+		// Provider takes the payments of new orders, "fake" is built in for
+		// local development and tests
+		Provider string
+		// WebhookSecrets by provider name sign the webhooks of the provider
+		WebhookSecrets map[string]string
+		// WebhookTolerance is how old or early a webhook signature may be
+		WebhookTolerance time.Duration
+	}
+
+	// Webhooks configures the delivery of order events to subscribers
+	Webhooks struct {
+		// PollInterval is how often due deliveries are looked for
+		PollInterval time.Duration
+		// BatchSize is how many deliveries are sent per poll
+		BatchSize int
+		// Timeout limits a single delivery request
+		Timeout time.Duration
+		// MaxAttempts after which a delivery is dead
+		MaxAttempts int
+		// RetryBaseDelay is the delay after the first failed attempt, doubled
+		// after every further one up to RetryMaxDelay
+		RetryBaseDelay time.Duration
+		RetryMaxDelay  time.Duration
+		// AllowPrivateNetworks lets subscriptions point at loopback,
+		// link-local and private addresses, for receivers on a dev machine
+		AllowPrivateNetworks bool
+	}
+
+	// Outbox configures the relay publishing domain events from the outbox
+	Outbox struct {
+		// Publisher is where events are published: log, http or memory
+		Publisher string
+		// URL receives the events of the http publisher
+		URL string
+		// PollInterval is how often unpublished events are looked for
+		PollInterval time.Duration
+		// BatchSize is how many events are published per poll
+		BatchSize int
+		// Timeout limits publishing a single event
+		Timeout time.Duration
+		// RetryBaseDelay is the delay after the first failed attempt, doubled
+		// after every further one up to RetryMaxDelay
+		RetryBaseDelay time.Duration
+		RetryMaxDelay  time.Duration
+	}
+
+	Idempotency struct {
+		// KeyTTL is how long a response is replayed for retries carrying
+		// the same Idempotency-Key
+		KeyTTL time.Duration
+		// InFlightLease is how long a request holds its key before storing
+		// a response. Retries get 409 until then, after it they run again,
+		// so it has to outlast the request timeouts.
+		InFlightLease time.Duration
+		// CleanupInterval is how often expired keys are deleted
+		CleanupInterval time.Duration
 	}
 )
 
@@ -42,6 +195,34 @@ func GetConfig(configPath ...string) *Config {
 		} else {
 			viper.AddConfigPath(".") // Default path
 		}
+		viper.SetDefault("pricing.defaultcurrency", "INR")
+		viper.SetDefault("idempotency.keyttl", "24h")
+		viper.SetDefault("idempotency.inflightlease", "2m")
+		viper.SetDefault("idempotency.cleanupinterval", "1h")
+		viper.SetDefault("orderpolicy.maxopenorders", 1)
+		viper.SetDefault("payments.provider", "fake")
+		viper.SetDefault("payments.webhooktolerance", "5m")
+		viper.SetDefault("server.requesttimeout", "30s")
+		viper.SetDefault("db.maxopenconns", 25)
+		viper.SetDefault("db.maxidleconns", 10)
+		viper.SetDefault("db.connmaxlifetime", "30m")
+		viper.SetDefault("db.connmaxidletime", "5m")
+		viper.SetDefault("db.statementtimeout", "30s")
+		viper.SetDefault("db.connectattempts", 8)
+		viper.SetDefault("db.connectretrydelay", "500ms")
+		viper.SetDefault("webhooks.pollinterval", "5s")
+		viper.SetDefault("webhooks.batchsize", 20)
+		viper.SetDefault("webhooks.timeout", "10s")
+		viper.SetDefault("webhooks.maxattempts", 8)
+		viper.SetDefault("webhooks.retrybasedelay", "30s")
+		viper.SetDefault("webhooks.retrymaxdelay", "1h")
+		viper.SetDefault("webhooks.allowprivatenetworks", false)
+		viper.SetDefault("outbox.publisher", "log")
+		viper.SetDefault("outbox.pollinterval", "1s")
+		viper.SetDefault("outbox.batchsize", 100)
+		viper.SetDefault("outbox.timeout", "10s")
+		viper.SetDefault("outbox.retrybasedelay", "5s")
+		viper.SetDefault("outbox.retrymaxdelay", "10m")
 		viper.AutomaticEnv()
 		viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
@@ -1,9 +1,23 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+)
 
 type Database interface {
 	GetDb() *gorm.DB
+	// WithTx runs fn in a transaction that is committed or rolled back
+	// before it returns, or in a savepoint when ctx is inside one already
+	WithTx(ctx context.Context, fn func(tx *gorm.DB) error) error
 	CloseDb(db *gorm.DB) error
-	AutoMigrateTables() error
+	MigrateUp() error
+	MigrateDown(steps int) error
+	MigrationStatus() ([]MigrationStatus, error)
+	// Ping checks that the database can be reached
+	Ping(ctx context.Context) error
+	// Stats reports the state of the connection pool
+	Stats() sql.DBStats
 }
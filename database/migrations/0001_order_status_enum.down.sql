@@ -0,0 +1 @@
+DROP TYPE IF EXISTS order_status;
@@ -0,0 +1,18 @@
+-- The order status enum lives in its own migration because labels added to an
+-- existing enum cannot be used until the transaction adding them commits.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_type WHERE typname = 'order_status') THEN
+        CREATE TYPE order_status AS ENUM ('pending', 'paid', 'picking', 'shipped', 'delivered', 'cancelled', 'returned');
+    END IF;
+END $$;
+
+-- Databases created before the order lifecycle only know 'unfulfilled' and 'fulfilled'
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'pending';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'paid';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'picking';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'shipped';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'delivered';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'cancelled';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'returned';
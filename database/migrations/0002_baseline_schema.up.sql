@@ -0,0 +1,163 @@
+-- Baseline schema. Every statement is idempotent so databases created by the
+-- former gorm AutoMigrate, at any earlier version of the service, are brought
+-- up to date as well as empty ones.
+
+CREATE TABLE IF NOT EXISTS customers (
+    id         uuid PRIMARY KEY,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz,
+    name       text,
+    email      text,
+    country    text
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id             uuid PRIMARY KEY,
+    created_at     timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at     timestamptz,
+    name           text,
+    category       text,
+    price_amount   bigint,
+    price_currency varchar(3),
+    stock          bigint
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id                   uuid PRIMARY KEY,
+    created_at           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at           timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at           timestamptz,
+    customer_id          uuid REFERENCES customers (id),
+    total_price_amount   bigint,
+    total_price_currency varchar(3),
+    status               order_status
+);
+
+CREATE TABLE IF NOT EXISTS order_items (
+    id                  uuid PRIMARY KEY,
+    created_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at          timestamptz,
+    order_id            uuid REFERENCES orders (id),
+    product_id          uuid REFERENCES products (id),
+    quantity            bigint,
+    unit_price_amount   bigint,
+    unit_price_currency varchar(3),
+    line_total_amount   bigint,
+    line_total_currency varchar(3)
+);
+
+-- Timestamps were added to existing tables over time. Rows that predate them
+-- are stamped with the time of the migration and count as never updated.
+DO $$
+DECLARE
+    tbl text;
+BEGIN
+    FOREACH tbl IN ARRAY ARRAY['customers', 'products', 'orders', 'order_items'] LOOP
+        EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP', tbl);
+
+        IF NOT EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = tbl AND column_name = 'updated_at'
+        ) THEN
+            EXECUTE format('ALTER TABLE %I ADD COLUMN updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP', tbl);
+            EXECUTE format('UPDATE %I SET updated_at = created_at', tbl);
+        END IF;
+
+        EXECUTE format('ALTER TABLE %I ADD COLUMN IF NOT EXISTS deleted_at timestamptz', tbl);
+    END LOOP;
+END $$;
+
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS price_amount bigint,
+    ADD COLUMN IF NOT EXISTS price_currency varchar(3),
+    ADD COLUMN IF NOT EXISTS stock bigint;
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS total_price_amount bigint,
+    ADD COLUMN IF NOT EXISTS total_price_currency varchar(3);
+
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS unit_price_amount bigint,
+    ADD COLUMN IF NOT EXISTS unit_price_currency varchar(3),
+    ADD COLUMN IF NOT EXISTS line_total_amount bigint,
+    ADD COLUMN IF NOT EXISTS line_total_currency varchar(3);
+
+-- Amounts used to be float columns in major units. Convert them to minor
+-- units of the configured default currency, set by the migration runner.
+DO $$
+DECLARE
+    currency text := current_setting('app.default_currency');
+    scale numeric := current_setting('app.default_currency_scale')::numeric;
+    col record;
+BEGIN
+    FOR col IN
+        SELECT * FROM (VALUES
+            ('products', 'price'),
+            ('orders', 'total_price'),
+            ('order_items', 'unit_price'),
+            ('order_items', 'line_total')
+        ) AS legacy (tbl, name)
+    LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = col.tbl AND column_name = col.name
+        ) THEN
+            EXECUTE format(
+                'UPDATE %1$I SET %2$I = ROUND(%3$I * $1)::bigint, %4$I = $2 WHERE %2$I IS NULL',
+                col.tbl, col.name || '_amount', col.name, col.name || '_currency'
+            ) USING scale, currency;
+            EXECUTE format('ALTER TABLE %I DROP COLUMN %I', col.tbl, col.name);
+        END IF;
+    END LOOP;
+END $$;
+
+-- Move orders still carrying the statuses used before the order lifecycle
+-- onto their current equivalents, and the column onto the enum.
+UPDATE orders SET status = 'pending' WHERE status::text = 'unfulfilled';
+UPDATE orders SET status = 'delivered' WHERE status::text = 'fulfilled';
+ALTER TABLE orders ALTER COLUMN status TYPE order_status USING status::text::order_status;
+
+-- Orders placed before order items existed linked their products through the
+-- order_products join table. Carry them over as single unit lines priced at
+-- the current product price, the best snapshot available.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.tables
+        WHERE table_schema = current_schema() AND table_name = 'order_products'
+    ) THEN
+        INSERT INTO order_items (id, order_id, product_id, quantity, unit_price_amount, unit_price_currency, line_total_amount, line_total_currency)
+        SELECT gen_random_uuid(), op.order_id, op.product_id, 1, pr.price_amount, pr.price_currency, pr.price_amount, pr.price_currency
+        FROM order_products op
+        JOIN products pr ON pr.id = op.product_id
+        WHERE NOT EXISTS (
+            SELECT 1 FROM order_items oi WHERE oi.order_id = op.order_id
+        );
+
+        DROP TABLE order_products;
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_customers_created_at ON customers (created_at);
+CREATE INDEX IF NOT EXISTS idx_customers_updated_at ON customers (updated_at);
+CREATE INDEX IF NOT EXISTS idx_customers_deleted_at ON customers (deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_products_created_at ON products (created_at);
+CREATE INDEX IF NOT EXISTS idx_products_updated_at ON products (updated_at);
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at);
+
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders (created_at);
+CREATE INDEX IF NOT EXISTS idx_orders_updated_at ON orders (updated_at);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_created ON orders (customer_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_created ON orders (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_status_created ON orders (status, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_orders_total_price ON orders (total_price_amount, id);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_created_at ON order_items (created_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_updated_at ON order_items (updated_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_deleted_at ON order_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_items_order_id ON order_items (order_id);
@@ -0,0 +1,14 @@
+-- Responses of requests sent with an Idempotency-Key header, replayed when a
+-- client retries the same request. A row without a response is still in flight.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    endpoint        text        NOT NULL,
+    key             text        NOT NULL,
+    fingerprint     text        NOT NULL,
+    response_status integer,
+    response_body   bytea,
+    created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at      timestamptz NOT NULL,
+    PRIMARY KEY (endpoint, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,4 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE orders DROP COLUMN IF EXISTS cancelled_by;
+ALTER TABLE orders DROP COLUMN IF EXISTS cancel_note;
+ALTER TABLE orders DROP COLUMN IF EXISTS cancel_reason;
@@ -0,0 +1,4 @@
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancel_reason text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancel_note text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_by text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cancelled_at timestamptz;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_customer_status;
+DROP TABLE IF EXISTS customer_order_limits;
@@ -0,0 +1,13 @@
+-- Per-customer overrides of the configured order policy
+CREATE TABLE IF NOT EXISTS customer_order_limits (
+    customer_id              uuid PRIMARY KEY REFERENCES customers (id) ON DELETE CASCADE,
+    max_open_orders          integer CHECK (max_open_orders >= 0),
+    max_order_value_amount   bigint CHECK (max_order_value_amount >= 0),
+    max_order_value_currency varchar(3),
+    created_at               timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at               timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((max_order_value_amount IS NULL) = (max_order_value_currency IS NULL))
+);
+
+-- Open orders are counted per customer on every new order
+CREATE INDEX IF NOT EXISTS idx_orders_customer_status ON orders (customer_id, status);
@@ -0,0 +1,8 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS discount_total_currency,
+    DROP COLUMN IF EXISTS discount_total_amount,
+    DROP COLUMN IF EXISTS subtotal_currency,
+    DROP COLUMN IF EXISTS subtotal_amount;
+
+DROP TABLE IF EXISTS order_discounts;
+DROP TABLE IF EXISTS coupons;
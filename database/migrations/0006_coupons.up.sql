@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    id                           uuid PRIMARY KEY,
+    created_at                   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at                   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at                   timestamptz,
+    code                         text NOT NULL,
+    type                         text NOT NULL CHECK (type IN ('percentage', 'fixed_amount', 'buy_x_get_y')),
+    percent_off                  integer NOT NULL DEFAULT 0,
+    amount_off                   bigint NOT NULL DEFAULT 0,
+    buy_quantity                 integer NOT NULL DEFAULT 0,
+    get_quantity                 integer NOT NULL DEFAULT 0,
+    category                     text NOT NULL DEFAULT '',
+    min_basket                   bigint NOT NULL DEFAULT 0,
+    currency                     varchar(3) NOT NULL DEFAULT '',
+    starts_at                    timestamptz,
+    ends_at                      timestamptz,
+    max_redemptions              integer,
+    max_redemptions_per_customer integer
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_coupons_code ON coupons (code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_coupons_created_at ON coupons (created_at);
+CREATE INDEX IF NOT EXISTS idx_coupons_updated_at ON coupons (updated_at);
+CREATE INDEX IF NOT EXISTS idx_coupons_deleted_at ON coupons (deleted_at);
+
+-- Coupons redeemed on orders, counted against the coupon's usage limits
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id              uuid PRIMARY KEY,
+    created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at      timestamptz,
+    order_id        uuid NOT NULL REFERENCES orders (id),
+    coupon_id       uuid NOT NULL REFERENCES coupons (id),
+    code            text NOT NULL,
+    amount_amount   bigint NOT NULL,
+    amount_currency varchar(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_discounts_order_id ON order_discounts (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_discounts_coupon_id ON order_discounts (coupon_id);
+CREATE INDEX IF NOT EXISTS idx_order_discounts_deleted_at ON order_discounts (deleted_at);
+
+-- Orders placed before coupons were not discounted
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS subtotal_amount bigint,
+    ADD COLUMN IF NOT EXISTS subtotal_currency varchar(3),
+    ADD COLUMN IF NOT EXISTS discount_total_amount bigint,
+    ADD COLUMN IF NOT EXISTS discount_total_currency varchar(3);
+
+UPDATE orders
+SET subtotal_amount = total_price_amount,
+    subtotal_currency = total_price_currency,
+    discount_total_amount = 0,
+    discount_total_currency = total_price_currency
+WHERE subtotal_amount IS NULL;
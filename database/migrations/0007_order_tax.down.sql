@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS prices_include_tax,
+    DROP COLUMN IF EXISTS tax_total_currency,
+    DROP COLUMN IF EXISTS tax_total_amount;
+
+DROP TABLE IF EXISTS order_tax_lines;
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS order_tax_lines (
+    id                uuid PRIMARY KEY,
+    created_at        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at        timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at        timestamptz,
+    order_id          uuid NOT NULL REFERENCES orders (id),
+    name              text NOT NULL,
+    rate_basis_points bigint NOT NULL,
+    taxable_amount    bigint NOT NULL,
+    taxable_currency  varchar(3) NOT NULL,
+    amount_amount     bigint NOT NULL,
+    amount_currency   varchar(3) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_tax_lines_order_id ON order_tax_lines (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_tax_lines_deleted_at ON order_tax_lines (deleted_at);
+
+-- Orders placed before tax was calculated were not taxed
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS tax_total_amount bigint,
+    ADD COLUMN IF NOT EXISTS tax_total_currency varchar(3),
+    ADD COLUMN IF NOT EXISTS prices_include_tax boolean NOT NULL DEFAULT false;
+
+UPDATE orders
+SET tax_total_amount = 0,
+    tax_total_currency = total_price_currency
+WHERE tax_total_amount IS NULL;
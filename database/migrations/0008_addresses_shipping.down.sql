@@ -0,0 +1,18 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping_cost_currency,
+    DROP COLUMN IF EXISTS shipping_cost_amount,
+    DROP COLUMN IF EXISTS shipping_weight_grams,
+    DROP COLUMN IF EXISTS shipping_zone,
+    DROP COLUMN IF EXISTS shipping_country,
+    DROP COLUMN IF EXISTS shipping_postal_code,
+    DROP COLUMN IF EXISTS shipping_state,
+    DROP COLUMN IF EXISTS shipping_city,
+    DROP COLUMN IF EXISTS shipping_line2,
+    DROP COLUMN IF EXISTS shipping_line1,
+    DROP COLUMN IF EXISTS shipping_recipient,
+    DROP COLUMN IF EXISTS shipping_address_id;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS weight_grams;
+
+DROP TABLE IF EXISTS addresses;
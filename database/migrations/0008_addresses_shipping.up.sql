@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS addresses (
+    id                  uuid PRIMARY KEY,
+    created_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at          timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at          timestamptz,
+    customer_id         uuid NOT NULL REFERENCES customers (id),
+    recipient           text NOT NULL,
+    line1               text NOT NULL,
+    line2               text NOT NULL DEFAULT '',
+    city                text NOT NULL,
+    state               text NOT NULL DEFAULT '',
+    postal_code         text NOT NULL DEFAULT '',
+    country             text NOT NULL,
+    is_default_billing  boolean NOT NULL DEFAULT false,
+    is_default_shipping boolean NOT NULL DEFAULT false
+);
+
+CREATE INDEX IF NOT EXISTS idx_addresses_customer_id ON addresses (customer_id);
+CREATE INDEX IF NOT EXISTS idx_addresses_deleted_at ON addresses (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default_billing ON addresses (customer_id) WHERE is_default_billing AND deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_addresses_default_shipping ON addresses (customer_id) WHERE is_default_shipping AND deleted_at IS NULL;
+
+-- Products without a weight ship for the price of the lightest bracket
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS weight_grams integer NOT NULL DEFAULT 0;
+
+-- Orders placed before addresses existed have no shipping address and were
+-- shipped for free
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS shipping_address_id uuid REFERENCES addresses (id),
+    ADD COLUMN IF NOT EXISTS shipping_recipient text,
+    ADD COLUMN IF NOT EXISTS shipping_line1 text,
+    ADD COLUMN IF NOT EXISTS shipping_line2 text,
+    ADD COLUMN IF NOT EXISTS shipping_city text,
+    ADD COLUMN IF NOT EXISTS shipping_state text,
+    ADD COLUMN IF NOT EXISTS shipping_postal_code text,
+    ADD COLUMN IF NOT EXISTS shipping_country text,
+    ADD COLUMN IF NOT EXISTS shipping_zone text,
+    ADD COLUMN IF NOT EXISTS shipping_weight_grams integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS shipping_cost_amount bigint,
+    ADD COLUMN IF NOT EXISTS shipping_cost_currency varchar(3);
+
+UPDATE orders
+SET shipping_cost_amount = 0,
+    shipping_cost_currency = total_price_currency
+WHERE shipping_cost_amount IS NULL;
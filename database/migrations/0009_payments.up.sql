@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS payments (
+    id                 uuid PRIMARY KEY,
+    created_at         timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at         timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at         timestamptz,
+    order_id           uuid NOT NULL REFERENCES orders (id),
+    provider           text NOT NULL,
+    provider_reference text NOT NULL DEFAULT '',
+    status             text NOT NULL,
+    amount_amount      bigint NOT NULL,
+    amount_currency    varchar(3) NOT NULL,
+    captured_amount    bigint NOT NULL DEFAULT 0,
+    captured_currency  varchar(3) NOT NULL,
+    refunded_amount    bigint NOT NULL DEFAULT 0,
+    refunded_currency  varchar(3) NOT NULL,
+    failure_reason     text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+CREATE INDEX IF NOT EXISTS idx_payments_deleted_at ON payments (deleted_at);
+
+-- An order is paid through at most one payment at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_active_order ON payments (order_id)
+    WHERE status IN ('authorized', 'captured', 'partially_refunded') AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS refunds (
+    id                 uuid PRIMARY KEY,
+    created_at         timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at         timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at         timestamptz,
+    payment_id         uuid NOT NULL REFERENCES payments (id),
+    provider_reference text NOT NULL,
+    amount_amount      bigint NOT NULL,
+    amount_currency    varchar(3) NOT NULL,
+    reason             text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_payment_id ON refunds (payment_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_deleted_at ON refunds (deleted_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_provider_reference;
+DROP INDEX IF EXISTS idx_refunds_provider_reference;
+DROP TABLE IF EXISTS payment_webhook_events;
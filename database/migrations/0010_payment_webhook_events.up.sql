@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+    id           uuid PRIMARY KEY,
+    created_at   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at   timestamptz,
+    provider     text NOT NULL,
+    event_id     text NOT NULL,
+    type         text NOT NULL,
+    order_id     uuid REFERENCES orders (id),
+    payment_id   uuid REFERENCES payments (id),
+    status       text NOT NULL,
+    error        text NOT NULL DEFAULT '',
+    payload      bytea NOT NULL,
+    processed_at timestamptz
+);
+
+-- Providers resend events until they are acknowledged, each is handled once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_webhook_events_provider_event ON payment_webhook_events (provider, event_id);
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_events_order_id ON payment_webhook_events (order_id);
+CREATE INDEX IF NOT EXISTS idx_payment_webhook_events_deleted_at ON payment_webhook_events (deleted_at);
+
+-- Refunds reported by webhooks are matched to the ones already recorded
+CREATE INDEX IF NOT EXISTS idx_refunds_provider_reference ON refunds (provider_reference);
+CREATE INDEX IF NOT EXISTS idx_payments_provider_reference ON payments (provider, provider_reference);
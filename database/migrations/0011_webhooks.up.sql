@@ -0,0 +1,45 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          uuid PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at  timestamptz,
+    url         text NOT NULL,
+    event_types jsonb NOT NULL,
+    secret      text NOT NULL,
+    active      boolean NOT NULL DEFAULT true
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               uuid PRIMARY KEY,
+    created_at       timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at       timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at       timestamptz,
+    subscription_id  uuid NOT NULL REFERENCES webhook_subscriptions (id),
+    event_id         uuid NOT NULL,
+    event_type       text NOT NULL,
+    payload          bytea NOT NULL,
+    status           text NOT NULL,
+    attempts         integer NOT NULL DEFAULT 0,
+    next_attempt_at  timestamptz NOT NULL,
+    last_status_code integer NOT NULL DEFAULT 0,
+    last_error       text NOT NULL DEFAULT '',
+    delivered_at     timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+-- The dispatcher only ever looks for pending deliveries that are due
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+    id          uuid PRIMARY KEY,
+    created_at  timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivery_id uuid NOT NULL REFERENCES webhook_deliveries (id),
+    status_code integer NOT NULL DEFAULT 0,
+    error       text NOT NULL DEFAULT '',
+    duration_ms bigint NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts (delivery_id);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              uuid PRIMARY KEY,
+    sequence        bigserial NOT NULL UNIQUE,
+    created_at      timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    aggregate_type  text NOT NULL,
+    aggregate_id    uuid NOT NULL,
+    event_type      text NOT NULL,
+    payload         bytea NOT NULL,
+    attempts        integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error      text NOT NULL DEFAULT '',
+    published_at    timestamptz
+);
+
+-- The relay only ever looks at unpublished events, in order and by aggregate
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events (sequence) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished_aggregate ON outbox_events (aggregate_type, aggregate_id, sequence) WHERE published_at IS NULL;
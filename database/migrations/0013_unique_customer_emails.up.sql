@@ -0,0 +1,16 @@
+-- Emails are unique among customers that are not deleted, ignoring case and
+-- surrounding spaces, which the service strips before storing them. Earlier
+-- versions of the service accepted any email, so existing ones are normalised
+-- first. Of customers sharing an email the earliest keeps it; the others get
+-- '+duplicate-<id>' appended so they can be found and merged by hand.
+UPDATE customers SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+
+UPDATE customers SET email = duplicate.email || '+duplicate-' || duplicate.id
+FROM (
+    SELECT id, email, row_number() OVER (PARTITION BY email ORDER BY created_at, id) AS position
+    FROM customers
+    WHERE deleted_at IS NULL
+) AS duplicate
+WHERE customers.id = duplicate.id AND duplicate.position > 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_email ON customers (email) WHERE deleted_at IS NULL;
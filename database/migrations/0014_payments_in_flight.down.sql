@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_payments_active_order;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_active_order ON payments (order_id)
+    WHERE status IN ('authorized', 'captured', 'partially_refunded') AND deleted_at IS NULL;
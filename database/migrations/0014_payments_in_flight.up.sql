@@ -0,0 +1,5 @@
+-- Payments waiting for their provider still hold the order, so it cannot be
+-- paid a second time meanwhile
+DROP INDEX IF EXISTS idx_payments_active_order;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_active_order ON payments (order_id)
+    WHERE status IN ('pending', 'authorized', 'capturing', 'captured', 'voiding', 'refunding', 'partially_refunded') AND deleted_at IS NULL;
@@ -1,12 +1,13 @@
 package database
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
 	"sync"
+	"time"
 
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
-	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
 	"github.com/sirupsen/logrus"
 
 	_ "github.com/lib/pq"
@@ -15,16 +16,19 @@ import (
 )
 
 type postgresDatabase struct {
-	Db *gorm.DB
+	Db   *gorm.DB
+	conf *config.Config
 }
 
 var (
 	once       sync.Once
 	DbInstance *postgresDatabase
+	connectErr error
 )
 
-// NewPostgresDatabase returns the new instance of postgres db
-func NewPostgresDatabase(conf *config.Config) Database {
+// NewPostgresDatabase returns the new instance of postgres db, retrying the
+// connection with backoff while the database cannot be reached
+func NewPostgresDatabase(conf *config.Config) (Database, error) {
 	once.Do(func() {
 		dsn := fmt.Sprintf(
 			"host=%s user=%s password=%s dbname=%s port=%d sslmode=%s TimeZone=%s",
@@ -36,18 +40,63 @@ func NewPostgresDatabase(conf *config.Config) Database {
 			conf.Db.SSLMode,
 			conf.Db.TimeZone,
 		)
+		if conf.Db.StatementTimeout > 0 {
+			dsn += fmt.Sprintf(" statement_timeout=%d", conf.Db.StatementTimeout.Milliseconds())
+		}
+
+		var db *gorm.DB
+		db, connectErr = connect(dsn, conf.Db)
+		if connectErr != nil {
+			return
+		}
 
-		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		sqlDb, err := db.DB()
 		if err != nil {
-			panic("failed to connect database")
+			connectErr = err
+			return
 		}
+		sqlDb.SetMaxOpenConns(conf.Db.MaxOpenConns)
+		sqlDb.SetMaxIdleConns(conf.Db.MaxIdleConns)
+		sqlDb.SetConnMaxLifetime(conf.Db.ConnMaxLifetime)
+		sqlDb.SetConnMaxIdleTime(conf.Db.ConnMaxIdleTime)
 
 		logrus.Printf("connected to '%v' database", conf.Db.DBName)
 
-		DbInstance = &postgresDatabase{Db: db}
+		DbInstance = &postgresDatabase{Db: db, conf: conf}
 	})
 
-	return DbInstance
+	if connectErr != nil {
+		return nil, connectErr
+	}
+	return DbInstance, nil
+}
+
+// connect opens the database, trying conf.ConnectAttempts times and doubling
+// the delay between tries, so the service can start before its database
+func connect(dsn string, conf *config.Db) (*gorm.DB, error) {
+	delay := conf.ConnectRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+		if err == nil {
+			return db, nil
+		}
+
+		// A failed ping leaves the pool open
+		if db != nil {
+			if sqlDb, dbErr := db.DB(); dbErr == nil {
+				sqlDb.Close()
+			}
+		}
+
+		if attempt >= conf.ConnectAttempts {
+			return nil, fmt.Errorf("failed to connect database after %d attempts: %w", attempt, err)
+		}
+
+		logrus.Warnf("Could not connect to database (attempt %d of %d), retrying in %v: %v", attempt, conf.ConnectAttempts, delay, err)
+		time.Sleep(delay)
+		delay *= 2
+	}
 }
 
 // CloseDb closes the underlying db connection
@@ -60,34 +109,26 @@ func (p *postgresDatabase) CloseDb(db *gorm.DB) error {
 	return sqlDb.Close()
 }
 
-func (p *postgresDatabase) AutoMigrateTables() error {
-
-	var exists bool
-	err := p.Db.Raw(`
-				SELECT EXISTS (
-				SELECT 1
-				FROM pg_type
-				WHERE typname = 'order_status'
-			);`).Scan(&exists).Error
+func (p *postgresDatabase) GetDb() *gorm.DB {
+	return DbInstance.Db
+}
 
+// Ping checks that the database can be reached
+func (p *postgresDatabase) Ping(ctx context.Context) error {
+	sqlDb, err := p.Db.DB()
 	if err != nil {
-		log.Println("Error checking if order_status enum exists:", err)
 		return err
 	}
 
-	// Create the enum type if it doesn't exist
-	if !exists {
-		err = p.Db.Exec(`CREATE TYPE order_status AS ENUM ('unfulfilled', 'fulfilled');`).Error
-		if err != nil {
-			log.Println("Error creating order_status enum:", err)
-			return err
-		}
-	}
-
-	return p.Db.AutoMigrate(&entities.Customer{}, &entities.Product{}, &entities.Order{})
-
+	return sqlDb.PingContext(ctx)
 }
 
-func (p *postgresDatabase) GetDb() *gorm.DB {
-	return DbInstance.Db
+// Stats reports the state of the connection pool
+func (p *postgresDatabase) Stats() sql.DBStats {
+	sqlDb, err := p.Db.DB()
+	if err != nil {
+		return sql.DBStats{}
+	}
+
+	return sqlDb.Stats()
 }
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// txKey is the context key of the transaction a unit of work runs in
+type txKey struct{}
+
+// WithTx runs fn as a unit of work on db. The transaction is committed when
+// fn returns nil and rolled back when it returns an error or panics, so it
+// never outlives the call. When ctx belongs to a unit of work already, as the
+// context of its tx does, fn runs in a savepoint of that transaction instead
+// and an error only rolls back what fn did.
+func WithTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if outer, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return outer.WithContext(ctx).Transaction(fn)
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(tx.WithContext(context.WithValue(ctx, txKey{}, tx)))
+	})
+}
+
+// WithTx runs fn as a unit of work on the database
+func (p *postgresDatabase) WithTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return WithTx(ctx, p.GetDb(), fn)
+}
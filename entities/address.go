@@ -0,0 +1,56 @@
+package entities
+
+import "github.com/google/uuid"
+
+// Address is a postal address of a customer. At most one address of a
+// customer is the default for billing and one the default for shipping.
+type Address struct {
+	BaseModel
+	CustomerID        uuid.UUID `gorm:"type:uuid;index" json:"customer_id"`
+	Recipient         string    `json:"recipient"`
+	Line1             string    `json:"line1"`
+	Line2             string    `json:"line2"`
+	City              string    `json:"city"`
+	State             string    `json:"state"`
+	PostalCode        string    `json:"postal_code"`
+	Country           string    `json:"country"`
+	IsDefaultBilling  bool      `json:"is_default_billing"`
+	IsDefaultShipping bool      `json:"is_default_shipping"`
+}
+
+// Snapshot returns the address as copied onto an order
+func (a Address) Snapshot() OrderAddress {
+	return OrderAddress{
+		Recipient:  a.Recipient,
+		Line1:      a.Line1,
+		Line2:      a.Line2,
+		City:       a.City,
+		State:      a.State,
+		PostalCode: a.PostalCode,
+		Country:    a.Country,
+	}
+}
+
+// OrderAddress is the address an order is shipped to, copied when the order
+// is placed so later changes to the customer's addresses never alter it
+type OrderAddress struct {
+	Recipient  string `json:"recipient"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+type AddressRequest struct {
+	Recipient         string `json:"recipient" validate:"required,max=200"`
+	Line1             string `json:"line1" validate:"required,max=200"`
+	Line2             string `json:"line2" validate:"max=200"`
+	City              string `json:"city" validate:"required,max=100"`
+	State             string `json:"state" validate:"max=100"`
+	PostalCode        string `json:"postal_code" validate:"max=20"`
+	Country           string `json:"country" validate:"required,max=100"`
+	IsDefaultBilling  bool   `json:"is_default_billing"`
+	IsDefaultShipping bool   `json:"is_default_shipping"`
+}
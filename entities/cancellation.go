@@ -0,0 +1,28 @@
+package entities
+
+// CancellationReason explains why an order was cancelled.
+type CancellationReason string
+
+const (
+	ReasonCustomerRequest CancellationReason = "customer_request"
+	ReasonDuplicateOrder  CancellationReason = "duplicate_order"
+	ReasonPaymentFailed   CancellationReason = "payment_failed"
+	ReasonOutOfStock      CancellationReason = "out_of_stock"
+	ReasonFraudSuspected  CancellationReason = "fraud_suspected"
+	ReasonOther           CancellationReason = "other"
+)
+
+// CancelledBy identifies who cancelled an order.
+type CancelledBy string
+
+const (
+	CancelledByCustomer CancelledBy = "customer"
+	CancelledByMerchant CancelledBy = "merchant"
+	CancelledBySystem   CancelledBy = "system"
+)
+
+type CancelOrderRequest struct {
+	Reason      CancellationReason `json:"reason" validate:"required,oneof=customer_request duplicate_order payment_failed out_of_stock fraud_suspected other"`
+	Note        string             `json:"note" validate:"max=500"`
+	CancelledBy CancelledBy        `json:"cancelled_by" validate:"required,oneof=customer merchant system"`
+}
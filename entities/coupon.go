@@ -0,0 +1,165 @@
+package entities
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+)
+
+// CouponType selects how a coupon's discount is calculated.
+type CouponType string
+
+const (
+	// CouponPercentage takes PercentOff percent off the eligible lines
+	CouponPercentage CouponType = "percentage"
+	// CouponFixedAmount takes AmountOff off the eligible lines
+	CouponFixedAmount CouponType = "fixed_amount"
+	// CouponBuyXGetY gives GetQuantity units free for every BuyQuantity
+	// units bought of the same product
+	CouponBuyXGetY CouponType = "buy_x_get_y"
+)
+
+var ErrCouponNotApplicable = errors.New("coupon not applicable")
+
+// Coupon is a discount customers can redeem with its code when ordering.
+// Amounts are in minor units of Currency. A coupon with a Category only
+// discounts products of that category.
+type Coupon struct {
+	BaseModel
+	Code                      string     `gorm:"uniqueIndex:idx_coupons_code,where:deleted_at IS NULL" json:"code"`
+	Type                      CouponType `json:"type"`
+	PercentOff                int        `json:"percent_off,omitempty"`
+	AmountOff                 int64      `json:"amount_off,omitempty"`
+	BuyQuantity               int        `json:"buy_quantity,omitempty"`
+	GetQuantity               int        `json:"get_quantity,omitempty"`
+	Category                  string     `json:"category,omitempty"`
+	MinBasket                 int64      `json:"min_basket,omitempty"`
+	Currency                  string     `gorm:"type:varchar(3)" json:"currency,omitempty"`
+	StartsAt                  *time.Time `json:"starts_at"`
+	EndsAt                    *time.Time `json:"ends_at"`
+	MaxRedemptions            *int       `json:"max_redemptions"`
+	MaxRedemptionsPerCustomer *int       `json:"max_redemptions_per_customer"`
+}
+
+// CouponLine is an order line as seen by a coupon
+type CouponLine struct {
+	Category  string
+	Quantity  int
+	UnitPrice money.Money
+}
+
+// NormalizeCouponCode returns the code in the form coupons are stored with
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// CheckValidAt reports whether the coupon may be redeemed at the given time
+func (c Coupon) CheckValidAt(now time.Time) error {
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return fmt.Errorf("%w: coupon %s is valid from %v", ErrCouponNotApplicable, c.Code, c.StartsAt.Format(time.RFC3339))
+	}
+	if c.EndsAt != nil && !now.Before(*c.EndsAt) {
+		return fmt.Errorf("%w: coupon %s expired at %v", ErrCouponNotApplicable, c.Code, c.EndsAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// AppliesTo reports whether the coupon discounts products of the category
+func (c Coupon) AppliesTo(category string) bool {
+	return c.Category == "" || strings.EqualFold(category, c.Category)
+}
+
+// Discount returns the amount the coupon takes off an order with the given
+// lines, which must share a currency. It never exceeds the eligible lines' total.
+func (c Coupon) Discount(lines []CouponLine) (money.Money, error) {
+	if len(lines) == 0 {
+		return money.Money{}, fmt.Errorf("%w: order has no lines", ErrCouponNotApplicable)
+	}
+
+	currency := lines[0].UnitPrice.Currency
+	subtotal, eligible := money.Zero(currency), money.Zero(currency)
+	var eligibleLines []CouponLine
+
+	for _, line := range lines {
+		lineTotal := line.UnitPrice.Multiply(int64(line.Quantity))
+
+		var err error
+		if subtotal, err = subtotal.Add(lineTotal); err != nil {
+			return money.Money{}, err
+		}
+
+		if c.AppliesTo(line.Category) {
+			eligible, _ = eligible.Add(lineTotal)
+			eligibleLines = append(eligibleLines, line)
+		}
+	}
+
+	if c.Currency != "" && c.Currency != currency {
+		return money.Money{}, fmt.Errorf("%w: coupon %s is for orders in %s", ErrCouponNotApplicable, c.Code, c.Currency)
+	}
+	if len(eligibleLines) == 0 {
+		return money.Money{}, fmt.Errorf("%w: coupon %s only applies to %s products", ErrCouponNotApplicable, c.Code, c.Category)
+	}
+	// Only the lines the coupon applies to count towards the minimum basket
+	if eligible.Amount < c.MinBasket {
+		return money.Money{}, fmt.Errorf("%w: coupon %s needs a basket of at least %v", ErrCouponNotApplicable, c.Code, money.New(c.MinBasket, currency))
+	}
+
+	var amount int64
+	switch c.Type {
+	case CouponPercentage:
+		// Rounded half up to the minor unit
+		amount = (eligible.Amount*int64(c.PercentOff) + 50) / 100
+	case CouponFixedAmount:
+		amount = c.AmountOff
+	case CouponBuyXGetY:
+		for _, line := range eligibleLines {
+			free := line.Quantity / (c.BuyQuantity + c.GetQuantity) * c.GetQuantity
+			amount += line.UnitPrice.Multiply(int64(free)).Amount
+		}
+		if amount == 0 {
+			return money.Money{}, fmt.Errorf("%w: coupon %s needs %d units of a product", ErrCouponNotApplicable, c.Code, c.BuyQuantity+c.GetQuantity)
+		}
+	default:
+		return money.Money{}, fmt.Errorf("%w: unknown coupon type %s", ErrCouponNotApplicable, c.Type)
+	}
+
+	return money.New(min(amount, eligible.Amount), currency), nil
+}
+
+// OrderDiscount is a coupon redeemed on an order and the amount it took off
+type OrderDiscount struct {
+	BaseModel
+	OrderID  uuid.UUID   `gorm:"type:uuid;index" json:"order_id"`
+	CouponID uuid.UUID   `gorm:"type:uuid;index" json:"coupon_id"`
+	Code     string      `json:"code"`
+	Amount   money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
+
+type CouponRequest struct {
+	Code                      string     `json:"code" validate:"required,max=64"`
+	Type                      CouponType `json:"type" validate:"required,oneof=percentage fixed_amount buy_x_get_y"`
+	PercentOff                int        `json:"percent_off" validate:"required_if=Type percentage,omitempty,min=1,max=100"`
+	AmountOff                 int64      `json:"amount_off" validate:"required_if=Type fixed_amount,omitempty,gte=1"`
+	BuyQuantity               int        `json:"buy_quantity" validate:"required_if=Type buy_x_get_y,omitempty,min=1"`
+	GetQuantity               int        `json:"get_quantity" validate:"required_if=Type buy_x_get_y,omitempty,min=1"`
+	Category                  string     `json:"category"`
+	MinBasket                 int64      `json:"min_basket" validate:"omitempty,gte=0"`
+	Currency                  string     `json:"currency" validate:"required_if=Type fixed_amount,required_with=MinBasket,omitempty,iso4217"`
+	StartsAt                  *time.Time `json:"starts_at"`
+	EndsAt                    *time.Time `json:"ends_at"`
+	MaxRedemptions            *int       `json:"max_redemptions" validate:"omitnil,min=1"`
+	MaxRedemptionsPerCustomer *int       `json:"max_redemptions_per_customer" validate:"omitnil,min=1"`
+}
+
+type CouponListQuery struct {
+	TimestampFilter
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=code -code created_at -created_at updated_at -updated_at"`
+	Type   string `query:"type" validate:"omitempty,oneof=percentage fixed_amount buy_x_get_y"`
+}
@@ -0,0 +1,21 @@
+package entities
+
+import "time"
+
+// IdempotencyKey records a request made with an Idempotency-Key header so that
+// retries of it are answered with the original response. ResponseStatus is nil
+// while the first request is still being processed.
+type IdempotencyKey struct {
+	Endpoint       string `gorm:"primaryKey"`
+	Key            string `gorm:"primaryKey"`
+	Fingerprint    string
+	ResponseStatus *int
+	ResponseBody   []byte
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+}
+
+// IsComplete reports whether the original response has been stored
+func (k *IdempotencyKey) IsComplete() bool {
+	return k.ResponseStatus != nil
+}
@@ -1,21 +1,18 @@
 package entities
 
 import (
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-// OrderStatus represents the status of an order.
-type OrderStatus string
-
-// Define constants for the order status values.
-const (
-	Unfulfilled OrderStatus = "unfulfilled"
-	Fulfilled   OrderStatus = "fulfilled"
-)
-
 type BaseModel struct {
-	ID uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"not null;default:CURRENT_TIMESTAMP;index" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 func (base *BaseModel) BeforeCreate(tx *gorm.DB) (err error) {
@@ -28,28 +25,135 @@ func (base *BaseModel) BeforeCreate(tx *gorm.DB) (err error) {
 type Customer struct {
 	BaseModel
 	Name    string  `json:"name" validate:"required"`
-	Email   string  `json:"email" validate:"required"`
+	Email   string  `gorm:"uniqueIndex:idx_customers_email,where:deleted_at IS NULL" json:"email" validate:"required,email"`
 	Country string  `json:"country"`
 	Order   []Order `gorm:"foreignKey:CustomerID" json:"orders"`
 }
 
 type Product struct {
 	BaseModel
-	Name     string  `json:"name"`
-	Category string  `json:"category"`
-	Price    float64 `json:"price"`
+	Name        string      `json:"name" validate:"required"`
+	Category    string      `json:"category" validate:"required"`
+	Price       money.Money `gorm:"embedded;embeddedPrefix:price_" json:"price"`
+	Stock       *int        `json:"stock" validate:"omitnil,gte=0"`
+	WeightGrams int         `json:"weight_grams" validate:"gte=0"`
 }
 
 type Order struct {
 	BaseModel
 	CustomerID uuid.UUID   `json:"customer_id"`
 	Customer   Customer    `gorm:"foreignKey:CustomerID" json:"-"`
-	Products   []Product   `gorm:"many2many:order_products;" json:"products"`
-	TotalPrice float64     `json:"total_price"`
-	Status     OrderStatus `json:"status" validate:"required,oneof=fulfilled unfulfilled"`
+	Items      []OrderItem `gorm:"foreignKey:OrderID" json:"items"`
+	Status     OrderStatus `gorm:"type:order_status" json:"status" validate:"required,oneof=pending paid picking shipped delivered cancelled returned"`
+
+	// Shipping: the address is a copy of the customer's address at ordering time
+	ShippingAddressID   *uuid.UUID   `gorm:"type:uuid" json:"shipping_address_id"`
+	ShippingAddress     OrderAddress `gorm:"embedded;embeddedPrefix:shipping_" json:"shipping_address"`
+	ShippingZone        string       `json:"shipping_zone"`
+	ShippingWeightGrams int          `json:"shipping_weight_grams"`
+
+	// Price breakdown: the total is the subtotal of the lines less discounts,
+	// plus tax unless the prices already included it, plus shipping
+	Subtotal         money.Money     `gorm:"embedded;embeddedPrefix:subtotal_" json:"subtotal"`
+	Discounts        []OrderDiscount `gorm:"foreignKey:OrderID" json:"discounts"`
+	DiscountTotal    money.Money     `gorm:"embedded;embeddedPrefix:discount_total_" json:"discount_total"`
+	TaxLines         []OrderTaxLine  `gorm:"foreignKey:OrderID" json:"tax_lines"`
+	TaxTotal         money.Money     `gorm:"embedded;embeddedPrefix:tax_total_" json:"tax_total"`
+	PricesIncludeTax bool            `json:"prices_include_tax"`
+	ShippingCost     money.Money     `gorm:"embedded;embeddedPrefix:shipping_cost_" json:"shipping_cost"`
+	TotalPrice       money.Money     `gorm:"embedded;embeddedPrefix:total_price_" json:"total_price"`
+
+	// Set when the order is cancelled through the cancel endpoint
+	CancelReason *CancellationReason `json:"cancel_reason,omitempty"`
+	CancelNote   *string             `json:"cancel_note,omitempty"`
+	CancelledBy  *CancelledBy        `json:"cancelled_by,omitempty"`
+	CancelledAt  *time.Time          `json:"cancelled_at,omitempty"`
+}
+
+// OrderItem is a line of an order. The unit price is copied from the product
+// when the order is placed so later price changes never alter the order.
+type OrderItem struct {
+	BaseModel
+	OrderID   uuid.UUID   `gorm:"type:uuid;index" json:"order_id"`
+	ProductID uuid.UUID   `gorm:"type:uuid" json:"product_id"`
+	Product   Product     `gorm:"foreignKey:ProductID" json:"product"`
+	Quantity  int         `json:"quantity"`
+	UnitPrice money.Money `gorm:"embedded;embeddedPrefix:unit_price_" json:"unit_price"`
+	LineTotal money.Money `gorm:"embedded;embeddedPrefix:line_total_" json:"line_total"`
 }
 
 type OrderRequest struct {
-	CustomerID string   `json:"customer_id" validate:"required"`
-	ProductIDs []string `json:"product_ids" validate:"required"`
+	CustomerID        string             `json:"customer_id" validate:"required,uuid_rfc4122"`
+	ShippingAddressID string             `json:"shipping_address_id" validate:"required,uuid_rfc4122"`
+	Items             []OrderItemRequest `json:"items" validate:"required,min=1,dive"`
+	CouponCode        string             `json:"coupon_code" validate:"omitempty,max=64"`
+}
+
+type OrderItemRequest struct {
+	ProductID string `json:"product_id" validate:"required,uuid_rfc4122"`
+	Quantity  int    `json:"quantity" validate:"required,min=1"`
+}
+
+// TimestampFilter restricts a listing to rows created or updated within a
+// range. Bounds are RFC 3339 times, the lower one inclusive.
+type TimestampFilter struct {
+	CreatedFrom string `query:"created_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	CreatedTo   string `query:"created_to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedFrom string `query:"updated_from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	UpdatedTo   string `query:"updated_to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+type OrderListQuery struct {
+	TimestampFilter
+	Limit      int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor     string `query:"cursor"`
+	Sort       string `query:"sort" validate:"omitempty,oneof=created_at -created_at updated_at -updated_at total -total"`
+	Status     string `query:"status"`
+	CustomerID string `query:"customer_id" validate:"omitempty,uuid_rfc4122"`
+	MinTotal   int64  `query:"min_total" validate:"omitempty,gte=0"`
+	MaxTotal   int64  `query:"max_total" validate:"omitempty,gte=0"`
+	Currency   string `query:"currency" validate:"required_with=MinTotal MaxTotal,omitempty,iso4217"`
+	Include    string `query:"include" validate:"omitempty,oneof=items"`
+}
+
+type OrderStatusRequest struct {
+	Status OrderStatus `json:"status" validate:"required"`
+}
+
+type CustomerRequest struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Country string `json:"country"`
+}
+
+type CustomerListQuery struct {
+	TimestampFilter
+	Limit      int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor     string `query:"cursor"`
+	Sort       string `query:"sort" validate:"omitempty,oneof=name -name email -email country -country created_at -created_at updated_at -updated_at"`
+	Country    string `query:"country"`
+	NamePrefix string `query:"name_prefix"`
+	Email      string `query:"email"`
+}
+
+type CustomerPatchRequest struct {
+	Name    *string `json:"name"`
+	Email   *string `json:"email"`
+	Country *string `json:"country"`
+}
+
+type ProductListQuery struct {
+	TimestampFilter
+	Limit    int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor   string `query:"cursor"`
+	Sort     string `query:"sort" validate:"omitempty,oneof=name -name price -price created_at -created_at updated_at -updated_at"`
+	Category string `query:"category"`
+}
+
+type ProductRequest struct {
+	Name        string       `json:"name" validate:"required"`
+	Category    string       `json:"category" validate:"required"`
+	Price       *money.Money `json:"price" validate:"required"`
+	Stock       *int         `json:"stock" validate:"omitnil,gte=0"`
+	WeightGrams int          `json:"weight_grams" validate:"gte=0"`
 }
@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CustomerOrderLimit overrides the configured order policy for one customer.
+// A nil limit falls back to the limit of the customer's country or the
+// default policy, and a limit of zero lifts it.
+type CustomerOrderLimit struct {
+	CustomerID            uuid.UUID    `gorm:"type:uuid;primaryKey" json:"customer_id"`
+	MaxOpenOrders         *int         `json:"max_open_orders"`
+	MaxOrderValue         *money.Money `gorm:"-" json:"max_order_value"`
+	MaxOrderValueAmount   *int64       `json:"-"`
+	MaxOrderValueCurrency *string      `gorm:"type:varchar(3)" json:"-"`
+	CreatedAt             time.Time    `json:"created_at"`
+	UpdatedAt             time.Time    `json:"updated_at"`
+}
+
+// BeforeSave stores MaxOrderValue in its amount and currency columns
+func (l *CustomerOrderLimit) BeforeSave(tx *gorm.DB) (err error) {
+	l.MaxOrderValueAmount, l.MaxOrderValueCurrency = nil, nil
+	if l.MaxOrderValue != nil {
+		l.MaxOrderValueAmount = &l.MaxOrderValue.Amount
+		l.MaxOrderValueCurrency = &l.MaxOrderValue.Currency
+	}
+	return
+}
+
+// AfterFind restores MaxOrderValue from its amount and currency columns
+func (l *CustomerOrderLimit) AfterFind(tx *gorm.DB) (err error) {
+	l.MaxOrderValue = nil
+	if l.MaxOrderValueAmount != nil && l.MaxOrderValueCurrency != nil {
+		value := money.New(*l.MaxOrderValueAmount, *l.MaxOrderValueCurrency)
+		l.MaxOrderValue = &value
+	}
+	return
+}
+
+type CustomerOrderLimitRequest struct {
+	MaxOpenOrders *int         `json:"max_open_orders" validate:"omitnil,gte=0"`
+	MaxOrderValue *money.Money `json:"max_order_value"`
+}
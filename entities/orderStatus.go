@@ -0,0 +1,69 @@
+package entities
+
+// OrderStatus represents the status of an order.
+type OrderStatus string
+
+// Define constants for the order status values.
+const (
+	Pending   OrderStatus = "pending"
+	Paid      OrderStatus = "paid"
+	Picking   OrderStatus = "picking"
+	Shipped   OrderStatus = "shipped"
+	Delivered OrderStatus = "delivered"
+	Cancelled OrderStatus = "cancelled"
+	Returned  OrderStatus = "returned"
+)
+
+// OrderStatuses lists every order status in lifecycle order.
+var OrderStatuses = []OrderStatus{Pending, Paid, Picking, Shipped, Delivered, Cancelled, Returned}
+
+// orderTransitions is the table of allowed status changes. A status missing
+// from the table is terminal.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	Pending:   {Paid, Cancelled},
+	Paid:      {Picking, Cancelled},
+	Picking:   {Shipped, Cancelled},
+	Shipped:   {Delivered},
+	Delivered: {Returned},
+}
+
+// IsValid reports whether s is a known order status.
+func (s OrderStatus) IsValid() bool {
+	for _, status := range OrderStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransitionTo reports whether an order in status s may move to next.
+func (s OrderStatus) CanTransitionTo(next OrderStatus) bool {
+	for _, status := range orderTransitions[s] {
+		if status == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen reports whether the order is still being processed, i.e. it has not
+// been delivered, cancelled or returned.
+func (s OrderStatus) IsOpen() bool {
+	switch s {
+	case Delivered, Cancelled, Returned:
+		return false
+	}
+	return true
+}
+
+// OpenOrderStatuses returns the statuses of orders that are still being processed.
+func OpenOrderStatuses() []OrderStatus {
+	var statuses []OrderStatus
+	for _, status := range OrderStatuses {
+		if status.IsOpen() {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
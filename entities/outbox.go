@@ -0,0 +1,37 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Aggregates whose changes are recorded in the outbox
+const (
+	AggregateCustomer = "customer"
+	AggregateOrder    = "order"
+)
+
+// Customer events recorded in the outbox, next to the order events
+const (
+	EventCustomerCreated = "customer.created"
+	EventCustomerUpdated = "customer.updated"
+	EventCustomerDeleted = "customer.deleted"
+)
+
+// OutboxEvent is a domain event written in the transaction of the change it
+// describes and published afterwards by the outbox relay.
+type OutboxEvent struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key"`
+	// Sequence is assigned by the database and orders the events
+	Sequence      int64 `gorm:"->"`
+	CreatedAt     time.Time
+	AggregateType string
+	AggregateID   uuid.UUID `gorm:"type:uuid"`
+	EventType     string
+	Payload       []byte `gorm:"type:bytea"`
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	PublishedAt   *time.Time
+}
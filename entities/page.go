@@ -0,0 +1,9 @@
+package entities
+
+// Page is the envelope returned by every paginated listing. NextCursor is
+// empty on the last page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+	TotalCount int64  `json:"total_count"`
+}
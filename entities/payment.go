@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+)
+
+// PaymentStatus is where a payment is in its lifecycle at the provider.
+type PaymentStatus string
+
+const (
+	PaymentAuthorized        PaymentStatus = "authorized"
+	PaymentCaptured          PaymentStatus = "captured"
+	PaymentPartiallyRefunded PaymentStatus = "partially_refunded"
+	PaymentRefunded          PaymentStatus = "refunded"
+	PaymentVoided            PaymentStatus = "voided"
+	PaymentFailed            PaymentStatus = "failed"
+
+	// Payments waiting for the provider to authorize, capture, void or
+	// refund them. Nothing else is done to them until its outcome is recorded.
+	PaymentPending   PaymentStatus = "pending"
+	PaymentCapturing PaymentStatus = "capturing"
+	PaymentVoiding   PaymentStatus = "voiding"
+	PaymentRefunding PaymentStatus = "refunding"
+)
+
+// ActivePaymentStatuses returns the statuses of payments that hold or have
+// taken the customer's money, or may be about to, so the order must not be
+// paid a second time.
+func ActivePaymentStatuses() []PaymentStatus {
+	return []PaymentStatus{PaymentPending, PaymentAuthorized, PaymentCapturing, PaymentCaptured, PaymentVoiding, PaymentRefunding, PaymentPartiallyRefunded}
+}
+
+// IsInFlight reports whether the provider is being called for the payment
+func (s PaymentStatus) IsInFlight() bool {
+	return s == PaymentPending || s == PaymentCapturing || s == PaymentVoiding || s == PaymentRefunding
+}
+
+// Payment is an attempt to pay for an order through a payment provider
+type Payment struct {
+	BaseModel
+	OrderID           uuid.UUID     `gorm:"type:uuid;index" json:"order_id"`
+	Provider          string        `json:"provider"`
+	ProviderReference string        `json:"provider_reference"`
+	Status            PaymentStatus `json:"status"`
+	Amount            money.Money   `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Captured          money.Money   `gorm:"embedded;embeddedPrefix:captured_" json:"captured"`
+	Refunded          money.Money   `gorm:"embedded;embeddedPrefix:refunded_" json:"refunded"`
+	FailureReason     string        `json:"failure_reason,omitempty"`
+	Refunds           []Refund      `json:"refunds"`
+}
+
+// Refund gives back part or all of the captured amount of a payment
+type Refund struct {
+	BaseModel
+	PaymentID         uuid.UUID   `gorm:"type:uuid;index" json:"payment_id"`
+	ProviderReference string      `json:"provider_reference"`
+	Amount            money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+	Reason            string      `json:"reason"`
+}
+
+type PaymentRequest struct {
+	PaymentMethod string `json:"payment_method" validate:"required,max=200"`
+	// AuthorizeOnly leaves the payment authorized to be captured later
+	AuthorizeOnly bool `json:"authorize_only"`
+}
+
+type RefundRequest struct {
+	// Amount in minor units, the whole remaining captured amount when omitted
+	Amount *int64 `json:"amount" validate:"omitempty,gte=1"`
+	Reason string `json:"reason" validate:"max=500"`
+}
@@ -0,0 +1,39 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentWebhookStatus is the outcome of handling a payment webhook.
+type PaymentWebhookStatus string
+
+const (
+	// WebhookReceived events are stored but not applied yet, which only
+	// lasts for the transaction handling them
+	WebhookReceived PaymentWebhookStatus = "received"
+	// WebhookProcessed events were applied to their payment and order
+	WebhookProcessed PaymentWebhookStatus = "processed"
+	// WebhookIgnored events are about payments we do not know or changes
+	// already recorded
+	WebhookIgnored PaymentWebhookStatus = "ignored"
+	// WebhookFailed events could not be applied, e.g. a refund of more than
+	// was captured
+	WebhookFailed PaymentWebhookStatus = "failed"
+)
+
+// PaymentWebhookEvent is a webhook received from a payment provider, kept
+// with its raw payload so it can be replayed
+type PaymentWebhookEvent struct {
+	BaseModel
+	Provider    string               `json:"provider"`
+	EventID     string               `json:"event_id"`
+	Type        string               `json:"type"`
+	OrderID     *uuid.UUID           `gorm:"type:uuid" json:"order_id"`
+	PaymentID   *uuid.UUID           `gorm:"type:uuid" json:"payment_id"`
+	Status      PaymentWebhookStatus `json:"status"`
+	Error       string               `json:"error,omitempty"`
+	Payload     []byte               `gorm:"type:bytea" json:"-"`
+	ProcessedAt *time.Time           `json:"processed_at"`
+}
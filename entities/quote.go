@@ -0,0 +1,26 @@
+package entities
+
+import (
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/google/uuid"
+)
+
+// OrderQuote is what an order would cost if it was placed now, and the order
+// policy rules that would stop it from being placed
+type OrderQuote struct {
+	CustomerID       uuid.UUID           `json:"customer_id"`
+	Items            []OrderItem         `json:"items"`
+	Subtotal         money.Money         `json:"subtotal"`
+	Discounts        []OrderDiscount     `json:"discounts"`
+	DiscountTotal    money.Money         `json:"discount_total"`
+	TaxLines         []OrderTaxLine      `json:"tax_lines"`
+	TaxTotal         money.Money         `json:"tax_total"`
+	PricesIncludeTax bool                `json:"prices_include_tax"`
+	ShippingAddress  OrderAddress        `json:"shipping_address"`
+	ShippingZone     string              `json:"shipping_zone"`
+	ShippingWeight   int                 `json:"shipping_weight_grams"`
+	ShippingCost     money.Money         `json:"shipping_cost"`
+	TotalPrice       money.Money         `json:"total_price"`
+	Violations       []*policy.Violation `json:"violations"`
+}
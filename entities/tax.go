@@ -0,0 +1,16 @@
+package entities
+
+import (
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+)
+
+// OrderTaxLine is the tax charged on an order at one rate
+type OrderTaxLine struct {
+	BaseModel
+	OrderID         uuid.UUID   `gorm:"type:uuid;index" json:"order_id"`
+	Name            string      `json:"name"`
+	RateBasisPoints int64       `json:"rate_basis_points"`
+	Taxable         money.Money `gorm:"embedded;embeddedPrefix:taxable_" json:"taxable"`
+	Amount          money.Money `gorm:"embedded;embeddedPrefix:amount_" json:"amount"`
+}
@@ -0,0 +1,141 @@
+package entities
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Order events sent to webhook subscribers
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderStatusChanged = "order.status_changed"
+)
+
+// WebhookEventTypes lists every event type that can be subscribed to.
+var WebhookEventTypes = []string{EventOrderCreated, EventOrderStatusChanged}
+
+// WebhookDeliveryStatus is where a delivery is in the delivery queue.
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliveryDelivered WebhookDeliveryStatus = "delivered"
+	// DeliveryDead deliveries failed every attempt and are only retried on request
+	DeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookSubscription sends the events of the subscribed types to a URL,
+// signed with the subscription's secret
+type WebhookSubscription struct {
+	BaseModel
+	URL        string        `json:"url"`
+	EventTypes EventTypeList `gorm:"type:jsonb" json:"event_types"`
+	// Secret is only ever returned when the subscription is created
+	Secret string `json:"-"`
+	Active bool   `json:"active"`
+}
+
+// EventTypeList is a list of event types stored as a JSON array
+type EventTypeList []string
+
+// Value implements driver.Valuer
+func (l EventTypeList) Value() (driver.Value, error) {
+	if l == nil {
+		l = EventTypeList{}
+	}
+
+	encoded, err := json.Marshal([]string(l))
+	return string(encoded), err
+}
+
+// Scan implements sql.Scanner
+func (l *EventTypeList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into EventTypeList", value)
+	}
+}
+
+// CreatedWebhookSubscription is the response to creating a subscription, the
+// only one carrying its secret
+type CreatedWebhookSubscription struct {
+	WebhookSubscription
+	Secret string `json:"secret"`
+}
+
+// WebhookEvent is the body of every delivery
+type WebhookEvent struct {
+	ID        uuid.UUID   `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// OrderStatusChange is the data of order.status_changed events
+type OrderStatusChange struct {
+	OrderID        uuid.UUID   `json:"order_id"`
+	CustomerID     uuid.UUID   `json:"customer_id"`
+	PreviousStatus OrderStatus `json:"previous_status"`
+	Status         OrderStatus `json:"status"`
+}
+
+// WebhookDelivery is an event queued for one subscription
+type WebhookDelivery struct {
+	BaseModel
+	SubscriptionID uuid.UUID                `gorm:"type:uuid;index" json:"subscription_id"`
+	EventID        uuid.UUID                `gorm:"type:uuid" json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Payload        []byte                   `gorm:"type:bytea" json:"-"`
+	Body           json.RawMessage          `gorm:"-" json:"payload"`
+	Status         WebhookDeliveryStatus    `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  time.Time                `json:"next_attempt_at"`
+	LastStatusCode int                      `json:"last_status_code"`
+	LastError      string                   `json:"last_error"`
+	DeliveredAt    *time.Time               `json:"delivered_at"`
+	AttemptLog     []WebhookDeliveryAttempt `gorm:"foreignKey:DeliveryID" json:"attempt_log"`
+}
+
+// AfterFind exposes the stored payload as JSON
+func (d *WebhookDelivery) AfterFind(tx *gorm.DB) error {
+	d.Body = json.RawMessage(d.Payload)
+	return nil
+}
+
+// WebhookDeliveryAttempt logs one attempt to send a delivery
+type WebhookDeliveryAttempt struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	DeliveryID uuid.UUID `gorm:"type:uuid;index" json:"delivery_id"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type WebhookSubscriptionRequest struct {
+	URL        string   `json:"url" validate:"required,url,max=2000"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,dive,oneof=order.created order.status_changed"`
+	// Secret signs the deliveries, one is generated when omitted
+	Secret string `json:"secret" validate:"omitempty,min=16,max=200"`
+	Active *bool  `json:"active"`
+}
+
+type WebhookDeliveryListQuery struct {
+	TimestampFilter
+	Limit  int    `query:"limit" validate:"omitempty,min=1,max=100"`
+	Cursor string `query:"cursor"`
+	Sort   string `query:"sort" validate:"omitempty,oneof=created_at -created_at updated_at -updated_at"`
+	Status string `query:"status" validate:"omitempty,oneof=pending delivered dead"`
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetCustomerAddresses handler returns every address of the customer
+func (cm CustomersHandler) GetCustomerAddresses(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching addresses.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("GET /api/customers/%v/addresses - Retrieving addresses", id)
+
+	addresses, errs := cm.CustomerRepo.GetCustomerAddresses(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching addresses: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, addresses)
+}
+
+// CreateAddress handler adds an address to the customer
+func (cm CustomersHandler) CreateAddress(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for creating an address.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	address, err := bindAddress(c)
+	if err != nil {
+		logger.Log.Warn("Invalid address: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("POST /api/customers/%v/addresses - Creating an address", id)
+
+	created, errs := cm.CustomerRepo.CreateAddress(c.Request().Context(), id, address)
+	if errs != nil {
+		logger.Log.Warn("Error creating an address: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusCreated, created)
+}
+
+// UpdateAddress handler replaces the details of an address of the customer
+func (cm CustomersHandler) UpdateAddress(c echo.Context) error {
+	id := c.Param("id")
+	addressId := c.Param("addressId")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for updating an address.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	_, err = uuid.Parse(addressId)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for updating an address.")
+		return c.JSON(http.StatusBadRequest, "invalid address id.")
+	}
+
+	address, err := bindAddress(c)
+	if err != nil {
+		logger.Log.Warn("Invalid address: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("PUT /api/customers/%v/addresses/%v - Updating an address", id, addressId)
+
+	updated, errs := cm.CustomerRepo.UpdateAddress(c.Request().Context(), id, addressId, address)
+	if errs != nil {
+		logger.Log.Warn("Error updating an address: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteAddress handler removes an address of the customer
+func (cm CustomersHandler) DeleteAddress(c echo.Context) error {
+	id := c.Param("id")
+	addressId := c.Param("addressId")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting an address.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	_, err = uuid.Parse(addressId)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting an address.")
+		return c.JSON(http.StatusBadRequest, "invalid address id.")
+	}
+
+	logger.Log.Infof("DELETE /api/customers/%v/addresses/%v - Deleting an address", id, addressId)
+
+	errs := cm.CustomerRepo.DeleteAddress(c.Request().Context(), id, addressId)
+	if errs != nil {
+		logger.Log.Warn("Error deleting an address: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// bindAddress parses and validates an address request body
+func bindAddress(c echo.Context) (*entities.Address, error) {
+	var addressRequest entities.AddressRequest
+
+	//parse request body
+	if err := c.Bind(&addressRequest); err != nil {
+		return nil, err
+	}
+
+	//validate request body
+	if err := c.Validate(&addressRequest); err != nil {
+		return nil, err
+	}
+
+	return &entities.Address{
+		Recipient:         addressRequest.Recipient,
+		Line1:             addressRequest.Line1,
+		Line2:             addressRequest.Line2,
+		City:              addressRequest.City,
+		State:             addressRequest.State,
+		PostalCode:        addressRequest.PostalCode,
+		Country:           addressRequest.Country,
+		IsDefaultBilling:  addressRequest.IsDefaultBilling,
+		IsDefaultShipping: addressRequest.IsDefaultShipping,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type CouponsHandler struct {
+	CouponRepo repository.CouponHandler
+}
+
+// NewCouponHandler returns the new instace of type CouponsHandler
+func NewCouponHandler(couponRepository repository.CouponHandler) CouponHandler {
+	return &CouponsHandler{
+		CouponRepo: couponRepository,
+	}
+}
+
+// GetAllCoupons returns a page of coupons matching the query parameters
+func (ch CouponsHandler) GetAllCoupons(c echo.Context) error {
+
+	logger.Log.Info("GET /api/coupons - Retrieving coupons")
+
+	var query entities.CouponListQuery
+
+	//parse query parameters
+	if err := c.Bind(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing coupons")
+		return c.JSON(http.StatusBadRequest, "invalid query parameters.")
+	}
+
+	//validate query parameters
+	if err := c.Validate(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing coupons: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	coupons, errs := ch.CouponRepo.GetAllCoupons(c.Request().Context(), query)
+	if errs != nil {
+		logger.Log.Error("Error retrieving coupons: ", errs)
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, coupons)
+}
+
+// GetCouponByID returns the coupon by couponId
+func (ch CouponsHandler) GetCouponByID(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching coupon.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	coupon, errs := ch.CouponRepo.GetCouponByID(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching coupon with id: ", id)
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, coupon)
+}
+
+// CreateCoupon handler adds a coupon customers can redeem when ordering
+func (ch CouponsHandler) CreateCoupon(c echo.Context) error {
+
+	logger.Log.Info("POST /api/coupons - Creating a new coupon")
+
+	coupon, err := bindCoupon(c)
+	if err != nil {
+		logger.Log.Warn("Invalid coupon payload: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	created, errs := ch.CouponRepo.CreateCoupon(c.Request().Context(), coupon)
+	if errs != nil {
+		logger.Log.Warn("Error creating a coupon: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusCreated, created)
+}
+
+// DeleteCoupon handler withdraws a coupon
+func (ch CouponsHandler) DeleteCoupon(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting coupon.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("DELETE /api/coupons/%v - Deleting coupon", id)
+
+	errs := ch.CouponRepo.DeleteCoupon(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error deleting coupon: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// bindCoupon parses and validates the coupon in the request body
+func bindCoupon(c echo.Context) (*entities.Coupon, error) {
+	var couponRequest entities.CouponRequest
+
+	//parse request body
+	if err := c.Bind(&couponRequest); err != nil {
+		return nil, err
+	}
+
+	couponRequest.Currency = strings.ToUpper(couponRequest.Currency)
+
+	//validate request body
+	if err := c.Validate(&couponRequest); err != nil {
+		return nil, err
+	}
+
+	if couponRequest.StartsAt != nil && couponRequest.EndsAt != nil && !couponRequest.EndsAt.After(*couponRequest.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+
+	return &entities.Coupon{
+		Code:                      entities.NormalizeCouponCode(couponRequest.Code),
+		Type:                      couponRequest.Type,
+		PercentOff:                couponRequest.PercentOff,
+		AmountOff:                 couponRequest.AmountOff,
+		BuyQuantity:               couponRequest.BuyQuantity,
+		GetQuantity:               couponRequest.GetQuantity,
+		Category:                  couponRequest.Category,
+		MinBasket:                 couponRequest.MinBasket,
+		Currency:                  couponRequest.Currency,
+		StartsAt:                  couponRequest.StartsAt,
+		EndsAt:                    couponRequest.EndsAt,
+		MaxRedemptions:            couponRequest.MaxRedemptions,
+		MaxRedemptionsPerCustomer: couponRequest.MaxRedemptionsPerCustomer,
+	}, nil
+}
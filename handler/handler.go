@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
@@ -22,12 +25,26 @@ func NewCustomerHandler(customerRepository repository.CustomerHandler) CustomerH
 	}
 }
 
-// GetAllCustomers returns the all available customers
+// GetAllCustomers returns a page of customers matching the query parameters
 func (cm CustomersHandler) GetAllCustomers(c echo.Context) error {
 
-	logger.Log.Info("GET /api/customers - Retrieving all customers")
+	logger.Log.Info("GET /api/customers - Retrieving customers")
 
-	customers, err := cm.CustomerRepo.GetAllCustomers()
+	var query entities.CustomerListQuery
+
+	//parse query parameters
+	if err := c.Bind(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing customers")
+		return c.JSON(http.StatusBadRequest, "invalid query parameters.")
+	}
+
+	//validate query parameters
+	if err := c.Validate(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing customers: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	customers, err := cm.CustomerRepo.GetAllCustomers(c.Request().Context(), query)
 	if err != nil {
 		logger.Log.Error("Error retrieving customers: ", err)
 		return c.JSON(err.HttpStatusCode(), err.Error())
@@ -47,7 +64,7 @@ func (cm CustomersHandler) GetCustomerByID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "invalid id.")
 	}
 
-	customer, errs := cm.CustomerRepo.GetCustomerByID(id)
+	customer, errs := cm.CustomerRepo.GetCustomerByID(c.Request().Context(), id)
 	if errs != nil {
 		logger.Log.Warn("Error fetching customer with id: ", id)
 		return c.JSON(errs.HttpStatusCode(), errs.Error())
@@ -57,41 +74,170 @@ func (cm CustomersHandler) GetCustomerByID(c echo.Context) error {
 
 }
 
-// CreateOrder handler
-func (cm CustomersHandler) CreateOrder(c echo.Context) error {
+// CreateCustomer handler registers a new customer
+func (cm CustomersHandler) CreateCustomer(c echo.Context) error {
 
-	logger.Log.Info("POST /api/orders - Creating a new order")
+	logger.Log.Info("POST /api/customers - Creating a new customer")
 
-	var orderRequest entities.OrderRequest
+	var customerRequest entities.CustomerRequest
 
 	//parse request body
-	err := c.Bind(&orderRequest)
+	err := c.Bind(&customerRequest)
 	if err != nil {
-		logger.Log.Warn("Invalid request payload for creating order")
-		return c.JSON(http.StatusInternalServerError, err)
+		logger.Log.Warn("Invalid request payload for creating customer")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	customer := &entities.Customer{
+		Name:    strings.TrimSpace(customerRequest.Name),
+		Email:   normalizeEmail(customerRequest.Email),
+		Country: strings.TrimSpace(customerRequest.Country),
 	}
 
-	//validate customerId
-	_, err = uuid.Parse(orderRequest.CustomerID)
+	//validate customer
+	if err := c.Validate(customer); err != nil {
+		logger.Log.Warn("Invalid customer: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	created, errs := cm.CustomerRepo.CreateCustomer(c.Request().Context(), customer)
+	if errs != nil {
+		logger.Log.Warn("Error creating a customer: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusCreated, created)
+}
+
+// UpdateCustomer handler replaces all details of a customer
+func (cm CustomersHandler) UpdateCustomer(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
 	if err != nil {
-		logger.Log.Warn("Invalid CustomerId: ", err)
-		return c.JSON(http.StatusInternalServerError, err)
+		logger.Log.Warn("Invalid request parameter for updating customer.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
 	}
 
-	//validate productIds
-	if len(orderRequest.ProductIDs) > 0 {
-		for _, id := range orderRequest.ProductIDs {
-			_, err = uuid.Parse(id)
-			if err != nil {
-				logger.Log.Warn("Invalid productId: ", err)
-				return c.JSON(http.StatusInternalServerError, err)
-			}
-		}
+	logger.Log.Infof("PUT /api/customers/%v - Updating customer", id)
+
+	var customerRequest entities.CustomerRequest
+
+	//parse request body
+	err = c.Bind(&customerRequest)
+	if err != nil {
+		logger.Log.Warn("Invalid request payload for updating customer")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	customer := &entities.Customer{
+		Name:    strings.TrimSpace(customerRequest.Name),
+		Email:   normalizeEmail(customerRequest.Email),
+		Country: strings.TrimSpace(customerRequest.Country),
+	}
+
+	//validate customer
+	if err := c.Validate(customer); err != nil {
+		logger.Log.Warn("Invalid customer: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	updated, errs := cm.CustomerRepo.UpdateCustomer(c.Request().Context(), id, customer)
+	if errs != nil {
+		logger.Log.Warn("Error updating customer: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// PatchCustomer handler updates only the provided details of a customer
+func (cm CustomersHandler) PatchCustomer(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for updating customer.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("PATCH /api/customers/%v - Updating customer", id)
+
+	var patchRequest entities.CustomerPatchRequest
+
+	//parse request body
+	err = c.Bind(&patchRequest)
+	if err != nil {
+		logger.Log.Warn("Invalid request payload for updating customer")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	customer, errs := cm.CustomerRepo.GetCustomerByID(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching customer with id: ", id)
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	if patchRequest.Name != nil {
+		customer.Name = strings.TrimSpace(*patchRequest.Name)
+	}
+	if patchRequest.Email != nil {
+		customer.Email = normalizeEmail(*patchRequest.Email)
+	}
+	if patchRequest.Country != nil {
+		customer.Country = strings.TrimSpace(*patchRequest.Country)
+	}
+
+	//validate customer
+	if err := c.Validate(customer); err != nil {
+		logger.Log.Warn("Invalid customer: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	updated, errs := cm.CustomerRepo.UpdateCustomer(c.Request().Context(), id, customer)
+	if errs != nil {
+		logger.Log.Warn("Error updating customer: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteCustomer handler removes a customer without open orders
+func (cm CustomersHandler) DeleteCustomer(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting customer.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("DELETE /api/customers/%v - Deleting customer", id)
+
+	errs := cm.CustomerRepo.DeleteCustomer(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error deleting customer: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// CreateOrder handler
+func (cm CustomersHandler) CreateOrder(c echo.Context) error {
+
+	logger.Log.Info("POST /api/orders - Creating a new order")
+
+	orderRequest, status, err := bindOrderRequest(c)
+	if err != nil {
+		logger.Log.Warn("Invalid order request: ", err)
+		return c.JSON(status, err.Error())
 	}
 
 	logger.Log.Infof("Processing order for customer_id: %v", orderRequest.CustomerID)
 
-	order, errs := cm.CustomerRepo.CreateOrder(orderRequest.CustomerID, orderRequest.ProductIDs)
+	order, errs := cm.CustomerRepo.CreateOrder(c.Request().Context(), orderRequest)
 	if errs != nil {
 		logger.Log.Warn("Error creating an order: ", errs.Error())
 		return c.JSON(errs.HttpStatusCode(), errs.Error())
@@ -100,6 +246,50 @@ func (cm CustomersHandler) CreateOrder(c echo.Context) error {
 	return c.JSON(http.StatusCreated, order)
 }
 
+// QuoteOrder handler prices an order without placing it
+func (cm CustomersHandler) QuoteOrder(c echo.Context) error {
+
+	logger.Log.Info("POST /api/orders/quote - Quoting an order")
+
+	orderRequest, status, err := bindOrderRequest(c)
+	if err != nil {
+		logger.Log.Warn("Invalid order request: ", err)
+		return c.JSON(status, err.Error())
+	}
+
+	quote, errs := cm.CustomerRepo.QuoteOrder(c.Request().Context(), orderRequest)
+	if errs != nil {
+		logger.Log.Warn("Error quoting an order: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, quote)
+}
+
+// bindOrderRequest parses and validates an order request, returning the
+// status to reply with when it is invalid
+func bindOrderRequest(c echo.Context) (entities.OrderRequest, int, error) {
+	var orderRequest entities.OrderRequest
+
+	//parse request body
+	if err := c.Bind(&orderRequest); err != nil {
+		return orderRequest, http.StatusBadRequest, errors.New("invalid request payload.")
+	}
+
+	//validate request body
+	if err := c.Validate(&orderRequest); err != nil {
+		return orderRequest, http.StatusBadRequest, err
+	}
+
+	//reject products listed more than once, quantities must be combined instead
+	duplicates := normalizeOrderItems(orderRequest.Items)
+	if len(duplicates) > 0 {
+		return orderRequest, http.StatusUnprocessableEntity, fmt.Errorf("Error: duplicate products in order: %v", strings.Join(duplicates, ", "))
+	}
+
+	return orderRequest, http.StatusOK, nil
+}
+
 // GetOrderByID handler retrieves order by id
 func (cm CustomersHandler) GetOrderByID(c echo.Context) error {
 
@@ -111,7 +301,7 @@ func (cm CustomersHandler) GetOrderByID(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, "invalid id.")
 	}
 
-	order, errs := cm.CustomerRepo.GetOrderByID(id)
+	order, errs := cm.CustomerRepo.GetOrderByID(c.Request().Context(), id)
 	if errs != nil {
 		logger.Log.Warn("Error fetching order by id: ", errs.Error())
 		return c.JSON(errs.HttpStatusCode(), errs.Error())
@@ -121,6 +311,184 @@ func (cm CustomersHandler) GetOrderByID(c echo.Context) error {
 
 }
 
+// GetOrders handler returns a page of orders matching the query parameters
+func (cm CustomersHandler) GetOrders(c echo.Context) error {
+
+	logger.Log.Info("GET /api/orders - Retrieving orders")
+
+	query, err := bindOrderListQuery(c)
+	if err != nil {
+		logger.Log.Warn("Invalid query parameters for listing orders: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	orders, errs := cm.CustomerRepo.GetOrders(c.Request().Context(), query)
+	if errs != nil {
+		logger.Log.Warn("Error retrieving orders: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, orders)
+}
+
+// GetCustomerOrders handler returns a page of the orders placed by a customer
+func (cm CustomersHandler) GetCustomerOrders(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching customer orders.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("GET /api/customers/%v/orders - Retrieving customer orders", id)
+
+	query, err := bindOrderListQuery(c)
+	if err != nil {
+		logger.Log.Warn("Invalid query parameters for listing customer orders: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	_, errs := cm.CustomerRepo.GetCustomerByID(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching customer with id: ", id)
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	query.CustomerID = id
+
+	orders, errs := cm.CustomerRepo.GetOrders(c.Request().Context(), query)
+	if errs != nil {
+		logger.Log.Warn("Error retrieving customer orders: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, orders)
+}
+
+// UpdateOrderStatus handler moves an order through its lifecycle
+func (cm CustomersHandler) UpdateOrderStatus(c echo.Context) error {
+
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter to update order status: ", err)
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	var statusRequest entities.OrderStatusRequest
+
+	//parse request body
+	err = c.Bind(&statusRequest)
+	if err != nil {
+		logger.Log.Warn("Invalid request payload for updating order status")
+		return c.JSON(http.StatusBadRequest, err)
+	}
+
+	//validate status
+	if !statusRequest.Status.IsValid() {
+		logger.Log.Warn("Invalid order status: ", statusRequest.Status)
+		return c.JSON(http.StatusBadRequest, "invalid status.")
+	}
+
+	logger.Log.Infof("PATCH /api/orders/%v/status - Moving order to %v", id, statusRequest.Status)
+
+	order, errs := cm.CustomerRepo.UpdateOrderStatus(c.Request().Context(), id, statusRequest.Status)
+	if errs != nil {
+		logger.Log.Warn("Error updating order status: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, order)
+
+}
+
+// CancelOrder handler cancels an order that has not shipped yet
+func (cm CustomersHandler) CancelOrder(c echo.Context) error {
+
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter to cancel order: ", err)
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	var cancelRequest entities.CancelOrderRequest
+
+	//parse request body
+	if err := c.Bind(&cancelRequest); err != nil {
+		logger.Log.Warn("Invalid request payload for cancelling order")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	//validate request body
+	if err := c.Validate(&cancelRequest); err != nil {
+		logger.Log.Warn("Invalid cancel request: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("POST /api/orders/%v/cancel - Cancelling order, reason %v", id, cancelRequest.Reason)
+
+	order, errs := cm.CustomerRepo.CancelOrder(c.Request().Context(), id, cancelRequest)
+	if errs != nil {
+		logger.Log.Warn("Error cancelling order: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, order)
+}
+
+// bindOrderListQuery parses and validates the order listing query parameters
+func bindOrderListQuery(c echo.Context) (entities.OrderListQuery, error) {
+	var query entities.OrderListQuery
+
+	//parse query parameters
+	if err := c.Bind(&query); err != nil {
+		return query, err
+	}
+	query.Currency = strings.ToUpper(query.Currency)
+
+	//validate query parameters
+	if err := c.Validate(&query); err != nil {
+		return query, err
+	}
+
+	if query.Status != "" {
+		for _, status := range strings.Split(query.Status, ",") {
+			if !entities.OrderStatus(status).IsValid() {
+				return query, fmt.Errorf("invalid status '%s'", status)
+			}
+		}
+	}
+
+	return query, nil
+}
+
+// normalizeOrderItems rewrites product ids in their canonical form and
+// returns the ids that appear on more than one item
+func normalizeOrderItems(items []entities.OrderItemRequest) []string {
+	seen := make(map[string]bool, len(items))
+	var duplicates []string
+
+	for i := range items {
+		items[i].ProductID = uuid.MustParse(items[i].ProductID).String()
+
+		if seen[items[i].ProductID] {
+			duplicates = append(duplicates, items[i].ProductID)
+		}
+		seen[items[i].ProductID] = true
+	}
+
+	return duplicates
+}
+
+// normalizeEmail trims and lowercases an email so uniqueness is case insensitive
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 // Middleware to log API latency
 func LatencyLogger(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
@@ -5,6 +5,57 @@ import "github.com/labstack/echo/v4"
 type CustomerHandler interface {
 	GetAllCustomers(c echo.Context) error
 	GetCustomerByID(c echo.Context) error
+	CreateCustomer(c echo.Context) error
+	UpdateCustomer(c echo.Context) error
+	PatchCustomer(c echo.Context) error
+	DeleteCustomer(c echo.Context) error
 	CreateOrder(c echo.Context) error
+	QuoteOrder(c echo.Context) error
 	GetOrderByID(c echo.Context) error
+	GetOrders(c echo.Context) error
+	GetCustomerOrders(c echo.Context) error
+	UpdateOrderStatus(c echo.Context) error
+	CancelOrder(c echo.Context) error
+	GetCustomerOrderLimit(c echo.Context) error
+	SetCustomerOrderLimit(c echo.Context) error
+	DeleteCustomerOrderLimit(c echo.Context) error
+	GetCustomerAddresses(c echo.Context) error
+	CreateAddress(c echo.Context) error
+	UpdateAddress(c echo.Context) error
+	DeleteAddress(c echo.Context) error
+}
+
+type ProductHandler interface {
+	GetAllProducts(c echo.Context) error
+	GetProductByID(c echo.Context) error
+	CreateProduct(c echo.Context) error
+	UpdateProduct(c echo.Context) error
+	DeleteProduct(c echo.Context) error
+}
+
+type CouponHandler interface {
+	GetAllCoupons(c echo.Context) error
+	GetCouponByID(c echo.Context) error
+	CreateCoupon(c echo.Context) error
+	DeleteCoupon(c echo.Context) error
+}
+
+type PaymentHandler interface {
+	GetOrderPayments(c echo.Context) error
+	CreatePayment(c echo.Context) error
+	CapturePayment(c echo.Context) error
+	VoidPayment(c echo.Context) error
+	RefundPayment(c echo.Context) error
+	ReceivePaymentWebhook(c echo.Context) error
+	ReplayPaymentWebhook(c echo.Context) error
+}
+
+type WebhookHandler interface {
+	GetWebhookSubscriptions(c echo.Context) error
+	GetWebhookSubscriptionByID(c echo.Context) error
+	CreateWebhookSubscription(c echo.Context) error
+	UpdateWebhookSubscription(c echo.Context) error
+	DeleteWebhookSubscription(c echo.Context) error
+	GetWebhookDeliveries(c echo.Context) error
+	RetryWebhookDelivery(c echo.Context) error
 }
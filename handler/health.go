@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/labstack/echo/v4"
+)
+
+// healthPingTimeout bounds how long the health check waits for the database
+const healthPingTimeout = 2 * time.Second
+
+// HealthReport is the body of the health endpoint
+type HealthReport struct {
+	Status   string         `json:"status"`
+	Database DatabaseHealth `json:"database"`
+}
+
+// DatabaseHealth reports whether the database answers and how its
+// connection pool is used
+type DatabaseHealth struct {
+	Status             string `json:"status"`
+	Error              string `json:"error,omitempty"`
+	MaxOpenConnections int    `json:"max_open_connections"`
+	OpenConnections    int    `json:"open_connections"`
+	InUse              int    `json:"in_use"`
+	Idle               int    `json:"idle"`
+	// WaitCount and WaitDurationMs add up every wait for a free connection
+	WaitCount         int64 `json:"wait_count"`
+	WaitDurationMs    int64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// Health handler pings the database and reports the connection pool. It
+// answers 503 Service Unavailable while the database cannot be reached.
+func Health(db database.Database) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		stats := db.Stats()
+		report := HealthReport{
+			Status: "ok",
+			Database: DatabaseHealth{
+				Status:             "ok",
+				MaxOpenConnections: stats.MaxOpenConnections,
+				OpenConnections:    stats.OpenConnections,
+				InUse:              stats.InUse,
+				Idle:               stats.Idle,
+				WaitCount:          stats.WaitCount,
+				WaitDurationMs:     stats.WaitDuration.Milliseconds(),
+				MaxIdleClosed:      stats.MaxIdleClosed,
+				MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+				MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), healthPingTimeout)
+		defer cancel()
+
+		if err := db.Ping(ctx); err != nil {
+			logger.Log.Error("Health check could not reach the database: ", err)
+			report.Status = "unavailable"
+			report.Database.Status = "unavailable"
+			// Driver errors name the host, user and database, which an
+			// unauthenticated endpoint must not give away
+			report.Database.Error = "database unavailable"
+			return c.JSON(http.StatusServiceUnavailable, report)
+		}
+
+		return c.JSON(http.StatusOK, report)
+	}
+}
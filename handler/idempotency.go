@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	// completeAttempts is how often storing a response is tried
+	completeAttempts = 3
+)
+
+// Idempotency replays the stored response when a request is retried with the
+// same Idempotency-Key header, so retries never repeat the side effects.
+// Requests without the header are passed through untouched. A request holds
+// its key for lease until its response is stored for ttl.
+func Idempotency(repo repository.IdempotencyHandler, ttl, lease time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				logger.Log.Warn("Idempotency-Key too long")
+				return c.JSON(http.StatusBadRequest, "Error: Idempotency-Key must be at most 255 characters")
+			}
+
+			//read the body and put it back for the handler
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				logger.Log.Warn("Error reading request body: ", err)
+				return c.JSON(http.StatusBadRequest, "invalid request payload.")
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			endpoint := c.Request().Method + " " + c.Path()
+			fingerprint := requestFingerprint(body)
+
+			record, created, errs := repo.ReserveIdempotencyKey(c.Request().Context(), endpoint, key, fingerprint, lease)
+			if errs != nil {
+				return c.JSON(errs.HttpStatusCode(), errs.Error())
+			}
+
+			if !created {
+				if record.Fingerprint != fingerprint {
+					logger.Log.Warnf("Idempotency-Key %v reused with a different request", key)
+					return c.JSON(http.StatusUnprocessableEntity, "Error: Idempotency-Key was already used with a different request")
+				}
+				if !record.IsComplete() {
+					logger.Log.Warnf("Idempotency-Key %v is still being processed", key)
+					return c.JSON(http.StatusConflict, "Error: request with this Idempotency-Key is being processed, retry later")
+				}
+
+				logger.Log.Infof("Replaying response for Idempotency-Key %v", key)
+				c.Response().Header().Set(IdempotentReplayedHeader, "true")
+				return c.Blob(*record.ResponseStatus, echo.MIMEApplicationJSON, record.ResponseBody)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			// The key is settled even when the request ran out of time
+			settleCtx := context.WithoutCancel(c.Request().Context())
+
+			//free the key when the request failed so the client can retry
+			release := func() {
+				if errs := repo.ReleaseIdempotencyKey(settleCtx, endpoint, key); errs != nil {
+					logger.Log.Error("Error releasing Idempotency-Key: ", errs.Error())
+				}
+			}
+
+			if err := next(c); err != nil {
+				release()
+				return err
+			}
+
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				release()
+				return nil
+			}
+
+			// The request took effect, so the key is never released: when the
+			// response cannot be stored, retries get 409 until the lease ends
+			for attempt := 1; ; attempt++ {
+				errs := repo.CompleteIdempotencyKey(settleCtx, endpoint, key, status, recorder.body.Bytes(), ttl)
+				if errs == nil {
+					break
+				}
+
+				if attempt == completeAttempts {
+					logger.Log.Errorf("Could not store response for Idempotency-Key %v, it stays reserved for %v: %v", key, lease, errs.Error())
+					break
+				}
+				logger.Log.Warnf("Error storing response for Idempotency-Key %v (attempt %d of %d): %v", key, attempt, completeAttempts, errs.Error())
+				time.Sleep(time.Duration(attempt) * 100 * time.Millisecond)
+			}
+
+			return nil
+		}
+	}
+}
+
+// requestFingerprint hashes the request body, ignoring JSON formatting and key
+// order, to detect a key reused for a different request
+func requestFingerprint(body []byte) string {
+	var payload interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&payload); err == nil {
+		if canonical, err := json.Marshal(payload); err == nil {
+			body = canonical
+		}
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// responseRecorder keeps a copy of the response body while writing it
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
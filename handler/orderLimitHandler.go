@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// GetCustomerOrderLimit handler returns the customer's override of the order policy
+func (cm CustomersHandler) GetCustomerOrderLimit(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching order limits.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("GET /api/customers/%v/order-limits - Retrieving order limits", id)
+
+	limit, errs := cm.CustomerRepo.GetCustomerOrderLimit(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching order limits: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, limit)
+}
+
+// SetCustomerOrderLimit handler replaces the customer's override of the order policy
+func (cm CustomersHandler) SetCustomerOrderLimit(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for updating order limits.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	var limitRequest entities.CustomerOrderLimitRequest
+
+	//parse request body
+	if err := c.Bind(&limitRequest); err != nil {
+		logger.Log.Warn("Invalid request payload for updating order limits")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	//validate request body
+	if err := c.Validate(&limitRequest); err != nil {
+		logger.Log.Warn("Invalid order limits: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("PUT /api/customers/%v/order-limits - Updating order limits", id)
+
+	limit := &entities.CustomerOrderLimit{MaxOpenOrders: limitRequest.MaxOpenOrders}
+	if limitRequest.MaxOrderValue != nil {
+		maxOrderValue := money.New(limitRequest.MaxOrderValue.Amount, limitRequest.MaxOrderValue.Currency)
+		limit.MaxOrderValue = &maxOrderValue
+	}
+
+	saved, errs := cm.CustomerRepo.SetCustomerOrderLimit(c.Request().Context(), id, limit)
+	if errs != nil {
+		logger.Log.Warn("Error updating order limits: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, saved)
+}
+
+// DeleteCustomerOrderLimit handler removes the customer's override of the order policy
+func (cm CustomersHandler) DeleteCustomerOrderLimit(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting order limits.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("DELETE /api/customers/%v/order-limits - Deleting order limits", id)
+
+	errs := cm.CustomerRepo.DeleteCustomerOrderLimit(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error deleting order limits: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
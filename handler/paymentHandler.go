@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// maxWebhookPayload is the largest webhook body read, in bytes
+const maxWebhookPayload = 1 << 20
+
+type PaymentsHandler struct {
+	PaymentRepo repository.PaymentHandler
+	Webhooks    *payment.WebhookVerifier
+}
+
+// NewPaymentHandler returns the new instace of type PaymentsHandler
+func NewPaymentHandler(paymentRepository repository.PaymentHandler, webhooks *payment.WebhookVerifier) PaymentHandler {
+	return &PaymentsHandler{
+		PaymentRepo: paymentRepository,
+		Webhooks:    webhooks,
+	}
+}
+
+// GetOrderPayments handler returns every payment attempt of the order
+func (ph PaymentsHandler) GetOrderPayments(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching payments.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("GET /api/orders/%v/payments - Retrieving payments", id)
+
+	payments, errs := ph.PaymentRepo.GetOrderPayments(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching payments: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, payments)
+}
+
+// CreatePayment handler pays for an order
+func (ph PaymentsHandler) CreatePayment(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for creating a payment.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	var paymentRequest entities.PaymentRequest
+
+	//parse request body
+	if err := c.Bind(&paymentRequest); err != nil {
+		logger.Log.Warn("Invalid request payload for creating a payment")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	//validate request body
+	if err := c.Validate(&paymentRequest); err != nil {
+		logger.Log.Warn("Invalid payment: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("POST /api/orders/%v/payments - Creating a payment", id)
+
+	payment, errs := ph.PaymentRepo.CreatePayment(c.Request().Context(), id, paymentRequest)
+	if errs != nil {
+		logger.Log.Warn("Error creating a payment: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusCreated, payment)
+}
+
+// CapturePayment handler captures an authorized payment
+func (ph PaymentsHandler) CapturePayment(c echo.Context) error {
+	id, paymentId, ok := paymentParams(c)
+	if !ok {
+		logger.Log.Warn("Invalid request parameter for capturing a payment.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("POST /api/orders/%v/payments/%v/capture - Capturing a payment", id, paymentId)
+
+	payment, errs := ph.PaymentRepo.CapturePayment(c.Request().Context(), id, paymentId)
+	if errs != nil {
+		logger.Log.Warn("Error capturing a payment: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// VoidPayment handler releases an authorized payment
+func (ph PaymentsHandler) VoidPayment(c echo.Context) error {
+	id, paymentId, ok := paymentParams(c)
+	if !ok {
+		logger.Log.Warn("Invalid request parameter for voiding a payment.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("POST /api/orders/%v/payments/%v/void - Voiding a payment", id, paymentId)
+
+	payment, errs := ph.PaymentRepo.VoidPayment(c.Request().Context(), id, paymentId)
+	if errs != nil {
+		logger.Log.Warn("Error voiding a payment: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// RefundPayment handler refunds a captured payment
+func (ph PaymentsHandler) RefundPayment(c echo.Context) error {
+	id, paymentId, ok := paymentParams(c)
+	if !ok {
+		logger.Log.Warn("Invalid request parameter for refunding a payment.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	var refundRequest entities.RefundRequest
+
+	//parse request body
+	if err := c.Bind(&refundRequest); err != nil {
+		logger.Log.Warn("Invalid request payload for refunding a payment")
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	//validate request body
+	if err := c.Validate(&refundRequest); err != nil {
+		logger.Log.Warn("Invalid refund: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("POST /api/orders/%v/payments/%v/refunds - Refunding a payment", id, paymentId)
+
+	payment, errs := ph.PaymentRepo.RefundPayment(c.Request().Context(), id, paymentId, refundRequest)
+	if errs != nil {
+		logger.Log.Warn("Error refunding a payment: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, payment)
+}
+
+// ReceivePaymentWebhook handler applies a signed notification of a payment
+// provider. Events received before are acknowledged without applying them
+// again.
+func (ph PaymentsHandler) ReceivePaymentWebhook(c echo.Context) error {
+	provider := c.Param("provider")
+
+	logger.Log.Infof("POST /webhooks/payments/%v - Receiving a payment webhook", provider)
+
+	if !ph.Webhooks.Accepts(provider) {
+		logger.Log.Warnf("Webhook of unknown payment provider %v", provider)
+		return c.JSON(http.StatusNotFound, "unknown payment provider.")
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxWebhookPayload))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			logger.Log.Warnf("Payment webhook larger than %d bytes", maxWebhookPayload)
+			return c.JSON(http.StatusRequestEntityTooLarge, "request payload too large.")
+		}
+
+		logger.Log.Warn("Could not read payment webhook: ", err)
+		return c.JSON(http.StatusBadRequest, "invalid request payload.")
+	}
+
+	//verify signature
+	err = ph.Webhooks.Verify(provider, c.Request().Header.Get(payment.TimestampHeader), c.Request().Header.Get(payment.SignatureHeader), payload, time.Now())
+	if err != nil {
+		logger.Log.Warn("Rejected payment webhook: ", err)
+		return c.JSON(http.StatusUnauthorized, "invalid signature.")
+	}
+
+	event, err := payment.ParseEvent(payload)
+	if err != nil {
+		logger.Log.Warn("Invalid payment webhook: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	record, duplicate, errs := ph.PaymentRepo.ReceivePaymentWebhook(c.Request().Context(), provider, event, payload)
+	if errs != nil {
+		logger.Log.Warn("Error handling payment webhook: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	if duplicate {
+		c.Response().Header().Set("Webhook-Duplicate", "true")
+	}
+
+	return c.JSON(http.StatusOK, record)
+}
+
+// ReplayPaymentWebhook handler applies a stored payment webhook again
+func (ph PaymentsHandler) ReplayPaymentWebhook(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for replaying a payment webhook.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("POST /api/webhooks/payments/events/%v/replay - Replaying a payment webhook", id)
+
+	record, errs := ph.PaymentRepo.ReplayPaymentWebhook(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error replaying payment webhook: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, record)
+}
+
+// paymentParams returns the order and payment ids of the path, reporting
+// whether both are valid
+func paymentParams(c echo.Context) (string, string, bool) {
+	id := c.Param("id")
+	paymentId := c.Param("paymentId")
+
+	if _, err := uuid.Parse(id); err != nil {
+		return id, paymentId, false
+	}
+	if _, err := uuid.Parse(paymentId); err != nil {
+		return id, paymentId, false
+	}
+
+	return id, paymentId, true
+}
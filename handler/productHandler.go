@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type ProductsHandler struct {
+	ProductRepo repository.ProductHandler
+}
+
+// NewProductHandler returns the new instace of type ProductsHandler
+func NewProductHandler(productRepository repository.ProductHandler) ProductHandler {
+	return &ProductsHandler{
+		ProductRepo: productRepository,
+	}
+}
+
+// GetAllProducts returns a page of products matching the query parameters
+func (ph ProductsHandler) GetAllProducts(c echo.Context) error {
+
+	logger.Log.Info("GET /api/products - Retrieving products")
+
+	var query entities.ProductListQuery
+
+	//parse query parameters
+	if err := c.Bind(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing products")
+		return c.JSON(http.StatusBadRequest, "invalid query parameters.")
+	}
+
+	//validate query parameters
+	if err := c.Validate(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing products: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	products, err := ph.ProductRepo.GetAllProducts(c.Request().Context(), query)
+	if err != nil {
+		logger.Log.Error("Error retrieving products: ", err)
+		return c.JSON(err.HttpStatusCode(), err.Error())
+	}
+
+	return c.JSON(http.StatusOK, products)
+
+}
+
+// GetProductByID returns the product by productId
+func (ph ProductsHandler) GetProductByID(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching product.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	product, errs := ph.ProductRepo.GetProductByID(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching product with id: ", id)
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, product)
+
+}
+
+// CreateProduct handler adds a product to the catalog
+func (ph ProductsHandler) CreateProduct(c echo.Context) error {
+
+	logger.Log.Info("POST /api/products - Creating a new product")
+
+	product, err := bindProduct(c)
+	if err != nil {
+		logger.Log.Warn("Invalid product payload: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	created, errs := ph.ProductRepo.CreateProduct(c.Request().Context(), product)
+	if errs != nil {
+		logger.Log.Warn("Error creating a product: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusCreated, created)
+}
+
+// UpdateProduct handler replaces the details of a product
+func (ph ProductsHandler) UpdateProduct(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for updating product.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("PUT /api/products/%v - Updating product", id)
+
+	product, err := bindProduct(c)
+	if err != nil {
+		logger.Log.Warn("Invalid product payload: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	updated, errs := ph.ProductRepo.UpdateProduct(c.Request().Context(), id, product)
+	if errs != nil {
+		logger.Log.Warn("Error updating product: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteProduct handler removes a product from the catalog
+func (ph ProductsHandler) DeleteProduct(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting product.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("DELETE /api/products/%v - Deleting product", id)
+
+	errs := ph.ProductRepo.DeleteProduct(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error deleting product: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// bindProduct parses and validates the product payload
+func bindProduct(c echo.Context) (*entities.Product, error) {
+	var productRequest entities.ProductRequest
+
+	//parse request body
+	if err := c.Bind(&productRequest); err != nil {
+		return nil, err
+	}
+
+	//validate request body
+	if err := c.Validate(&productRequest); err != nil {
+		return nil, err
+	}
+
+	return &entities.Product{
+		Name:        productRequest.Name,
+		Category:    productRequest.Category,
+		Price:       money.New(productRequest.Price.Amount, productRequest.Price.Currency),
+		Stock:       productRequest.Stock,
+		WeightGrams: productRequest.WeightGrams,
+	}, nil
+}
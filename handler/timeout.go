@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/labstack/echo/v4"
+)
+
+// RequestTimeout gives every request a deadline on its context: the one of
+// its route in routeTimeouts, keyed "METHOD /path", or timeout. Queries still
+// running at the deadline are cancelled, and the server error they end in is
+// answered 504 Gateway Timeout.
+func RequestTimeout(timeout time.Duration, routeTimeouts map[string]time.Duration) echo.MiddlewareFunc {
+	// viper lowercases the keys of the configuration
+	byRoute := make(map[string]time.Duration, len(routeTimeouts))
+	for route, routeTimeout := range routeTimeouts {
+		byRoute[strings.ToLower(route)] = routeTimeout
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Request().Method + " " + c.Path()
+
+			deadline := timeout
+			if routeTimeout, ok := byRoute[strings.ToLower(route)]; ok {
+				deadline = routeTimeout
+			}
+			if deadline <= 0 {
+				return next(c)
+			}
+
+			ctx, cancel := context.WithTimeout(c.Request().Context(), deadline)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+
+			c.Response().Before(func() {
+				if c.Response().Status >= http.StatusInternalServerError && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					logger.Log.Warnf("%v timed out after %v", route, deadline)
+					c.Response().Status = http.StatusGatewayTimeout
+				}
+			})
+
+			return next(c)
+		}
+	}
+}
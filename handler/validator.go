@@ -0,0 +1,20 @@
+package handler
+
+import (
+	"github.com/go-playground/validator/v10"
+)
+
+// RequestValidator validates request payloads using their `validate` struct tags
+type RequestValidator struct {
+	validator *validator.Validate
+}
+
+// NewRequestValidator returns the new instance of RequestValidator
+func NewRequestValidator() *RequestValidator {
+	return &RequestValidator{validator: validator.New()}
+}
+
+// Validate implements echo.Validator
+func (rv *RequestValidator) Validate(i interface{}) error {
+	return rv.validator.Struct(i)
+}
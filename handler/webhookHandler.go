@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+type WebhooksHandler struct {
+	WebhookRepo repository.WebhookHandler
+	URLs        *webhook.URLGuard
+}
+
+// NewWebhookHandler returns the new instace of type WebhooksHandler
+func NewWebhookHandler(webhookRepository repository.WebhookHandler, urls *webhook.URLGuard) WebhookHandler {
+	return &WebhooksHandler{
+		WebhookRepo: webhookRepository,
+		URLs:        urls,
+	}
+}
+
+// GetWebhookSubscriptions handler returns every webhook subscription
+func (wh WebhooksHandler) GetWebhookSubscriptions(c echo.Context) error {
+
+	logger.Log.Info("GET /api/webhooks - Retrieving webhook subscriptions")
+
+	subscriptions, errs := wh.WebhookRepo.GetWebhookSubscriptions(c.Request().Context())
+	if errs != nil {
+		logger.Log.Warn("Error fetching webhook subscriptions: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, subscriptions)
+}
+
+// GetWebhookSubscriptionByID handler returns a webhook subscription by its Id
+func (wh WebhooksHandler) GetWebhookSubscriptionByID(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching a webhook subscription.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("GET /api/webhooks/%v - Retrieving webhook subscription", id)
+
+	subscription, errs := wh.WebhookRepo.GetWebhookSubscriptionByID(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error fetching webhook subscription: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, subscription)
+}
+
+// CreateWebhookSubscription handler subscribes a URL to order events
+func (wh WebhooksHandler) CreateWebhookSubscription(c echo.Context) error {
+
+	logger.Log.Info("POST /api/webhooks - Creating a webhook subscription")
+
+	subscription, err := wh.bindWebhookSubscription(c)
+	if err != nil {
+		logger.Log.Warn("Invalid webhook subscription: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	created, errs := wh.WebhookRepo.CreateWebhookSubscription(c.Request().Context(), subscription)
+	if errs != nil {
+		logger.Log.Warn("Error creating a webhook subscription: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusCreated, entities.CreatedWebhookSubscription{WebhookSubscription: *created, Secret: created.Secret})
+}
+
+// UpdateWebhookSubscription handler replaces a webhook subscription
+func (wh WebhooksHandler) UpdateWebhookSubscription(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for updating a webhook subscription.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	subscription, err := wh.bindWebhookSubscription(c)
+	if err != nil {
+		logger.Log.Warn("Invalid webhook subscription: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("PUT /api/webhooks/%v - Updating webhook subscription", id)
+
+	updated, errs := wh.WebhookRepo.UpdateWebhookSubscription(c.Request().Context(), id, subscription)
+	if errs != nil {
+		logger.Log.Warn("Error updating a webhook subscription: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhookSubscription handler removes a webhook subscription
+func (wh WebhooksHandler) DeleteWebhookSubscription(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for deleting a webhook subscription.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	logger.Log.Infof("DELETE /api/webhooks/%v - Deleting webhook subscription", id)
+
+	errs := wh.WebhookRepo.DeleteWebhookSubscription(c.Request().Context(), id)
+	if errs != nil {
+		logger.Log.Warn("Error deleting a webhook subscription: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries handler returns a page of the delivery log of a subscription
+func (wh WebhooksHandler) GetWebhookDeliveries(c echo.Context) error {
+	id := c.Param("id")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for fetching webhook deliveries.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	var query entities.WebhookDeliveryListQuery
+
+	//parse query parameters
+	if err := c.Bind(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing webhook deliveries")
+		return c.JSON(http.StatusBadRequest, "invalid query parameters.")
+	}
+
+	//validate query parameters
+	if err := c.Validate(&query); err != nil {
+		logger.Log.Warn("Invalid query parameters for listing webhook deliveries: ", err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	logger.Log.Infof("GET /api/webhooks/%v/deliveries - Retrieving webhook deliveries", id)
+
+	page, errs := wh.WebhookRepo.GetWebhookDeliveries(c.Request().Context(), id, query)
+	if errs != nil {
+		logger.Log.Warn("Error fetching webhook deliveries: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+// RetryWebhookDelivery handler queues a dead delivery again
+func (wh WebhooksHandler) RetryWebhookDelivery(c echo.Context) error {
+	id := c.Param("id")
+	deliveryId := c.Param("deliveryId")
+
+	_, err := uuid.Parse(id)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for retrying a webhook delivery.")
+		return c.JSON(http.StatusBadRequest, "invalid id.")
+	}
+
+	_, err = uuid.Parse(deliveryId)
+	if err != nil {
+		logger.Log.Warn("Invalid request parameter for retrying a webhook delivery.")
+		return c.JSON(http.StatusBadRequest, "invalid delivery id.")
+	}
+
+	logger.Log.Infof("POST /api/webhooks/%v/deliveries/%v/retry - Retrying webhook delivery", id, deliveryId)
+
+	delivery, errs := wh.WebhookRepo.RetryWebhookDelivery(c.Request().Context(), id, deliveryId)
+	if errs != nil {
+		logger.Log.Warn("Error retrying webhook delivery: ", errs.Error())
+		return c.JSON(errs.HttpStatusCode(), errs.Error())
+	}
+
+	return c.JSON(http.StatusOK, delivery)
+}
+
+// bindWebhookSubscription parses and validates a webhook subscription request
+// body, including where its URL points
+func (wh WebhooksHandler) bindWebhookSubscription(c echo.Context) (*entities.WebhookSubscription, error) {
+	var subscriptionRequest entities.WebhookSubscriptionRequest
+
+	//parse request body
+	if err := c.Bind(&subscriptionRequest); err != nil {
+		return nil, err
+	}
+
+	//validate request body
+	if err := c.Validate(&subscriptionRequest); err != nil {
+		return nil, err
+	}
+
+	if err := wh.URLs.Check(c.Request().Context(), subscriptionRequest.URL); err != nil {
+		return nil, err
+	}
+
+	active := true
+	if subscriptionRequest.Active != nil {
+		active = *subscriptionRequest.Active
+	}
+
+	return &entities.WebhookSubscription{
+		URL:        subscriptionRequest.URL,
+		EventTypes: subscriptionRequest.EventTypes,
+		Secret:     subscriptionRequest.Secret,
+		Active:     active,
+	}, nil
+}
@@ -2,21 +2,40 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/outbox"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/server"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	os.Exit(run())
+}
+
+// run starts the service, or runs the migrate command, and returns the exit
+// code once it stops. Returning instead of exiting lets the deferred calls
+// close the database.
+func run() int {
 	// Initialize config and database
 	config := config.GetConfig()
-	db := database.NewPostgresDatabase(config)
+	db, err := database.NewPostgresDatabase(config)
+	if err != nil {
+		logrus.Error("Error connecting to database: ", err)
+		return 1
+	}
 
 	// Defer database close to ensure it shuts down at the end
 	defer func() {
@@ -27,26 +46,72 @@ func main() {
 		logrus.Print("Database has been closed!")
 	}()
 
+	// "migrate up|down [n]|status" manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(db, os.Args[2:]); err != nil {
+			logrus.Error("Error migrating tables: ", err)
+			return 1
+		}
+		return 0
+	}
+
 	//migrate the tables
-	if err := db.AutoMigrateTables(); err != nil {
+	if err := db.MigrateUp(); err != nil {
 		logrus.Error("Error migrating tables: ", err)
+		return 1
+	}
+
+	publisher, err := outbox.NewPublisher(*config.Outbox)
+	if err != nil {
+		logrus.Error("Error creating outbox publisher: ", err)
+		return 1
 	}
 
 	// Start the server
 	srv := server.NewEchoServer(config, db)
+	serverErr := make(chan error, 1)
 	go func() {
-		if err := srv.Start(); err != nil {
-			logrus.Fatal("Error starting server: ", err)
+		if err := srv.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
 		}
 	}()
 
+	// Deliver queued order events to webhook subscribers, publish the outbox
+	// and delete expired idempotency keys in the background
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	background.Add(3)
+	go func() {
+		defer background.Done()
+		webhook.NewDispatcher(repository.NewWebhookRepository(db), *config.Webhooks).Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		outbox.NewRelay(repository.NewOutboxRepository(db), publisher, *config.Outbox).Run(backgroundCtx)
+	}()
+	go func() {
+		defer background.Done()
+		purgeIdempotencyKeys(backgroundCtx, repository.NewIdempotencyRepository(db), config.Idempotency.CleanupInterval)
+	}()
+
+	// Let the dispatcher and relay finish the batch they are sending
+	defer func() {
+		stopBackground()
+		background.Wait()
+	}()
+
 	// Listen for shutdown signals
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
-	// Block until we receive a shutdown signal
-	<-quit
-	logrus.Println("Shutdown signal received. Shutting down gracefully...")
+	// Block until we receive a shutdown signal or the server fails
+	select {
+	case <-quit:
+		logrus.Println("Shutdown signal received. Shutting down gracefully...")
+	case err := <-serverErr:
+		logrus.Error("Error starting server: ", err)
+		return 1
+	}
 
 	// Create a context with a timeout for the server shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -54,8 +119,68 @@ func main() {
 
 	// Attempt to shut down the server gracefully
 	if err := srv.Shutdown(ctx); err != nil {
-		logrus.Fatal("Server forced to shutdown: ", err)
+		logrus.Error("Server forced to shutdown: ", err)
+		return 1
 	}
 
 	logrus.Println("Server exited gracefully")
+	return 0
+}
+
+// runMigrate applies, reverts or lists the schema migrations
+func runMigrate(db database.Database, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate up|down [steps]|status")
+	}
+
+	switch args[0] {
+	case "up":
+		return db.MigrateUp()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		return db.MigrateDown(steps)
+	case "status":
+		statuses, err := db.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys every interval until
+// ctx is done
+func purgeIdempotencyKeys(ctx context.Context, keys repository.IdempotencyHandler, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if deleted, errs := keys.DeleteExpiredIdempotencyKeys(ctx, time.Now()); errs != nil {
+			logrus.Error("Error deleting expired idempotency keys: ", errs.Error())
+		} else if deleted > 0 {
+			logrus.Printf("Deleted %d expired idempotency keys", deleted)
+		}
+	}
 }
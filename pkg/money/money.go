@@ -0,0 +1,134 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// minorUnitExponents lists the ISO 4217 currencies that do not use two
+// decimal places for their minor unit.
+var minorUnitExponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// Money is an amount expressed in the minor unit of its currency, e.g. cents
+// for USD or paise for INR, so arithmetic never suffers from float rounding.
+type Money struct {
+	Amount   int64  `json:"amount" validate:"gte=0"`
+	Currency string `gorm:"type:varchar(3)" json:"currency" validate:"required,iso4217"`
+}
+
+// New returns the amount in minor units of the given currency
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// FromMajor converts an amount in major units, e.g. 12.50, to Money
+func FromMajor(amount float64, currency string) Money {
+	currency = strings.ToUpper(currency)
+	return Money{
+		Amount:   int64(math.Round(amount * math.Pow10(Exponent(currency)))),
+		Currency: currency,
+	}
+}
+
+// Exponent returns the number of decimal places of the currency's minor unit
+func Exponent(currency string) int {
+	if exponent, ok := minorUnitExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Zero returns a zero amount in the given currency
+func Zero(currency string) Money {
+	return New(0, currency)
+}
+
+// Add returns the sum of both amounts, which must share a currency
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Subtract returns the difference of both amounts, which must share a currency
+func (m Money) Subtract(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return Money{Amount: m.Amount - other.Amount, Currency: m.Currency}, nil
+}
+
+// Multiply returns the amount multiplied by quantity
+func (m Money) Multiply(quantity int64) Money {
+	return Money{Amount: m.Amount * quantity, Currency: m.Currency}
+}
+
+// Allocate splits the amount in proportion to the weights, handing the minor
+// units left over by rounding down to the largest remainders so that the parts
+// always add up to the amount. Without any positive weight it returns zeros.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+	var total int64
+	for i, weight := range weights {
+		parts[i] = Zero(m.Currency)
+		total += max(weight, 0)
+	}
+	if total == 0 {
+		return parts
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		share := m.Amount * max(weight, 0)
+		parts[i].Amount = share / total
+		remainders[i] = share % total
+		allocated += parts[i].Amount
+	}
+
+	for left := m.Amount - allocated; left > 0; left-- {
+		largest := 0
+		for i := range remainders {
+			if remainders[i] > remainders[largest] {
+				largest = i
+			}
+		}
+		parts[largest].Amount++
+		remainders[largest] = -1
+	}
+
+	return parts
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// String formats the amount in major units followed by the currency code
+func (m Money) String() string {
+	exponent := Exponent(m.Currency)
+	if exponent == 0 {
+		return fmt.Sprintf("%d %s", m.Amount, m.Currency)
+	}
+
+	sign := ""
+	amount := m.Amount
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	unit := int64(math.Pow10(exponent))
+	return fmt.Sprintf("%s%d.%0*d %s", sign, amount/unit, exponent, amount%unit, m.Currency)
+}
@@ -0,0 +1,128 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
+)
+
+// Message is a domain event recorded in the outbox together with the change
+// it describes
+type Message struct {
+	ID string
+	// Sequence orders the messages of the outbox, and so of every aggregate
+	Sequence      int64
+	AggregateType string
+	AggregateID   string
+	EventType     string
+	Payload       []byte
+	CreatedAt     time.Time
+	// Attempts made before this one
+	Attempts int
+}
+
+// Store keeps the outbox. Claimed messages are not handed out again until
+// the lease ends, and no message is handed out while an earlier message of
+// its aggregate is leased or waiting for a retry.
+type Store interface {
+	ClaimMessages(now time.Time, limit int, lease time.Duration) ([]Message, error)
+	MarkPublished(message Message, at time.Time) error
+	MarkFailed(message Message, reason string, retryAt time.Time) error
+}
+
+// Relay publishes the messages of the store, oldest first. A message is
+// marked published only after the publisher accepted it, so every message
+// is published at least once and may be published again after a crash.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	conf      config.Outbox
+	now       func() time.Time
+}
+
+// NewRelay returns a relay from the store to the publisher
+func NewRelay(store Store, publisher Publisher, conf config.Outbox) *Relay {
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		conf:      conf,
+		now:       time.Now,
+	}
+}
+
+// Run relays messages every poll interval until ctx is done
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RelayDue(ctx); err != nil {
+			logger.Log.Error("Error relaying outbox events: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayDue publishes one batch of due messages. Once a message fails, the
+// later messages of its aggregate in the batch are left for after its retry.
+func (r *Relay) RelayDue(ctx context.Context) error {
+	// The batch stays leased for as long as publishing every message of it
+	// can take, plus one timeout to spare for the store
+	lease := time.Duration(r.conf.BatchSize+1) * r.conf.Timeout
+	leaseEnds := r.now().Add(lease)
+
+	messages, err := r.store.ClaimMessages(r.now(), r.conf.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	blocked := map[string]bool{}
+	for _, message := range messages {
+		// Once a message could still be publishing when the lease ends,
+		// another relay may claim it and the later messages of its
+		// aggregate, so the rest of the batch is left to be claimed again
+		if r.now().Add(r.conf.Timeout).After(leaseEnds) {
+			logger.Log.Warnf("Outbox lease ends before event %v can be published, leaving the rest of the batch", message.ID)
+			break
+		}
+
+		aggregate := message.AggregateType + "/" + message.AggregateID
+		if blocked[aggregate] {
+			continue
+		}
+
+		if err := r.publish(ctx, message); err != nil {
+			blocked[aggregate] = true
+
+			retryAt := r.now().Add(webhook.Backoff(message.Attempts+1, r.conf.RetryBaseDelay, r.conf.RetryMaxDelay))
+			logger.Log.Warnf("Could not publish outbox event %v, retrying at %v: %v", message.ID, retryAt, err)
+			if err := r.store.MarkFailed(message, err.Error(), retryAt); err != nil {
+				logger.Log.Errorf("Could not record failure of outbox event %v: %v", message.ID, err)
+			}
+			continue
+		}
+
+		if err := r.store.MarkPublished(message, r.now()); err != nil {
+			// Published again once the lease ends
+			blocked[aggregate] = true
+			logger.Log.Errorf("Could not mark outbox event %v published: %v", message.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Relay) publish(ctx context.Context, message Message) error {
+	ctx, cancel := context.WithTimeout(ctx, r.conf.Timeout)
+	defer cancel()
+
+	return r.publisher.Publish(ctx, message)
+}
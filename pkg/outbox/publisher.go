@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnknownPublisher is returned for a publisher name that is not built in
+var ErrUnknownPublisher = errors.New("unknown outbox publisher")
+
+// Publisher hands messages on to their consumers
+type Publisher interface {
+	Publish(ctx context.Context, message Message) error
+}
+
+// NewPublisher returns the publisher named by conf.Publisher
+func NewPublisher(conf config.Outbox) (Publisher, error) {
+	switch conf.Publisher {
+	case "log":
+		return LogPublisher{}, nil
+	case "http":
+		if conf.URL == "" {
+			return nil, errors.New("the http outbox publisher needs a url")
+		}
+		return NewHTTPPublisher(conf.URL), nil
+	case "memory":
+		return &MemoryPublisher{}, nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownPublisher, conf.Publisher)
+	}
+}
+
+// LogPublisher writes messages to the application log
+type LogPublisher struct{}
+
+func (LogPublisher) Publish(ctx context.Context, message Message) error {
+	logger.Log.WithFields(logrus.Fields{
+		"event_id":       message.ID,
+		"sequence":       message.Sequence,
+		"aggregate_type": message.AggregateType,
+		"aggregate_id":   message.AggregateID,
+	}).Infof("Event %v: %s", message.EventType, message.Payload)
+	return nil
+}
+
+// HTTPPublisher POSTs every message as JSON to a URL. Consumers drop
+// redelivered messages by their id.
+type HTTPPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPublisher returns a publisher posting to url
+func NewHTTPPublisher(url string) *HTTPPublisher {
+	return &HTTPPublisher{url: url, client: &http.Client{}}
+}
+
+// envelope is the body posted by the HTTPPublisher
+type envelope struct {
+	ID            string          `json:"id"`
+	Sequence      int64           `json:"sequence"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Type          string          `json:"type"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+func (p *HTTPPublisher) Publish(ctx context.Context, message Message) error {
+	body, err := json.Marshal(envelope{
+		ID:            message.ID,
+		Sequence:      message.Sequence,
+		AggregateType: message.AggregateType,
+		AggregateID:   message.AggregateID,
+		Type:          message.EventType,
+		CreatedAt:     message.CreatedAt,
+		Data:          message.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("consumer responded %s", resp.Status)
+	}
+	return nil
+}
+
+// MemoryPublisher keeps published messages in memory, for tests and local
+// development
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, message Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+// Messages returns the messages published so far, in order
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]Message(nil), p.messages...)
+}
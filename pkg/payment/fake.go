@@ -0,0 +1,118 @@
+package payment
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+)
+
+// FakeDeclinedMethod is the payment method the fake provider always declines
+const FakeDeclinedMethod = "tok_declined"
+
+// FakeProvider keeps payments in memory and approves every payment method
+// except FakeDeclinedMethod. It is meant for local development and tests.
+type FakeProvider struct {
+	mu       sync.Mutex
+	payments map[string]*fakePayment
+}
+
+type fakePayment struct {
+	authorized money.Money
+	captured   money.Money
+	refunded   money.Money
+	voided     bool
+}
+
+// NewFakeProvider returns an empty fake provider
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{payments: map[string]*fakePayment{}}
+}
+
+func (f *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *FakeProvider) Authorize(request AuthorizeRequest) (Transaction, error) {
+	if request.PaymentMethod == FakeDeclinedMethod {
+		return Transaction{}, fmt.Errorf("%w: card declined by issuer", ErrDeclined)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	reference := "fake_auth_" + uuid.NewString()
+	f.payments[reference] = &fakePayment{
+		authorized: request.Amount,
+		captured:   money.Zero(request.Amount.Currency),
+		refunded:   money.Zero(request.Amount.Currency),
+	}
+	return Transaction{Reference: reference}, nil
+}
+
+func (f *FakeProvider) Capture(reference string, amount money.Money) (Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, err := f.payment(reference)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if payment.voided || !payment.captured.IsZero() {
+		return Transaction{}, fmt.Errorf("authorization %s can no longer be captured", reference)
+	}
+	if amount.Currency != payment.authorized.Currency || amount.Amount > payment.authorized.Amount {
+		return Transaction{}, fmt.Errorf("cannot capture %v of an authorization of %v", amount, payment.authorized)
+	}
+
+	payment.captured = amount
+	return Transaction{Reference: "fake_capture_" + uuid.NewString()}, nil
+}
+
+func (f *FakeProvider) Refund(reference string, amount money.Money) (Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, err := f.payment(reference)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	refunded, err := payment.refunded.Add(amount)
+	if err != nil {
+		return Transaction{}, err
+	}
+	if refunded.Amount > payment.captured.Amount {
+		return Transaction{}, fmt.Errorf("cannot refund %v of a capture of %v", refunded, payment.captured)
+	}
+
+	payment.refunded = refunded
+	return Transaction{Reference: "fake_refund_" + uuid.NewString()}, nil
+}
+
+func (f *FakeProvider) Void(reference string) (Transaction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, err := f.payment(reference)
+	if err != nil {
+		return Transaction{}, err
+	}
+
+	if !payment.captured.IsZero() {
+		return Transaction{}, fmt.Errorf("authorization %s is already captured", reference)
+	}
+
+	payment.voided = true
+	return Transaction{Reference: "fake_void_" + uuid.NewString()}, nil
+}
+
+func (f *FakeProvider) payment(reference string) (*fakePayment, error) {
+	payment, ok := f.payments[reference]
+	if !ok {
+		return nil, fmt.Errorf("unknown authorization %s", reference)
+	}
+	return payment, nil
+}
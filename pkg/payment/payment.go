@@ -0,0 +1,64 @@
+package payment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+)
+
+var (
+	// ErrDeclined is returned when the provider refuses to authorize a payment
+	ErrDeclined = errors.New("payment declined")
+	// ErrUnknownProvider is returned for a provider name that is not registered
+	ErrUnknownProvider = errors.New("unknown payment provider")
+)
+
+// AuthorizeRequest asks a provider to reserve the amount of an order on the
+// customer's payment method
+type AuthorizeRequest struct {
+	OrderID       string
+	Amount        money.Money
+	PaymentMethod string
+}
+
+// Transaction is the provider's record of an authorization, capture, refund
+// or void
+type Transaction struct {
+	// Reference identifies the transaction at the provider
+	Reference string
+}
+
+// Provider moves money through an external payment service. Capture, Refund
+// and Void take the reference of the authorization.
+type Provider interface {
+	Name() string
+	Authorize(request AuthorizeRequest) (Transaction, error)
+	Capture(reference string, amount money.Money) (Transaction, error)
+	Refund(reference string, amount money.Money) (Transaction, error)
+	Void(reference string) (Transaction, error)
+}
+
+// Registry looks providers up by name
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry returns a registry of the given providers
+func NewRegistry(providers ...Provider) *Registry {
+	registry := &Registry{providers: map[string]Provider{}}
+	for _, provider := range providers {
+		registry.providers[strings.ToLower(provider.Name())] = provider
+	}
+	return registry
+}
+
+// Get returns the provider registered under name
+func (r *Registry) Get(name string) (Provider, error) {
+	provider, ok := r.providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, name)
+	}
+	return provider, nil
+}
@@ -0,0 +1,135 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+)
+
+// Headers carrying the signature of a webhook
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+)
+
+// Webhook event types
+const (
+	EventCaptured = "payment.captured"
+	EventRefunded = "payment.refunded"
+	EventVoided   = "payment.voided"
+	EventFailed   = "payment.failed"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidEvent     = errors.New("invalid webhook event")
+)
+
+// Event is a notification from a provider about one of its payments
+type Event struct {
+	// ID identifies the event at the provider, resent events share it
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// PaymentReference is the reference of the authorization the event is about
+	PaymentReference string `json:"payment_reference"`
+	// Reference identifies the capture or refund the event reports
+	Reference string       `json:"reference"`
+	Amount    *money.Money `json:"amount"`
+	Reason    string       `json:"reason"`
+}
+
+// ParseEvent decodes and checks a webhook payload
+func ParseEvent(payload []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+
+	if event.ID == "" || event.PaymentReference == "" {
+		return Event{}, fmt.Errorf("%w: id and payment_reference are required", ErrInvalidEvent)
+	}
+
+	switch event.Type {
+	case EventCaptured, EventVoided, EventFailed:
+	case EventRefunded:
+		if event.Amount == nil || event.Amount.Amount <= 0 || event.Reference == "" {
+			return Event{}, fmt.Errorf("%w: refunds need a positive amount and a reference", ErrInvalidEvent)
+		}
+	default:
+		return Event{}, fmt.Errorf("%w: unknown type '%s'", ErrInvalidEvent, event.Type)
+	}
+
+	return event, nil
+}
+
+// WebhookVerifier checks that webhooks were signed by the provider with the
+// secret shared with it
+type WebhookVerifier struct {
+	secrets   map[string]string
+	tolerance time.Duration
+}
+
+// DefaultWebhookTolerance is used when no positive tolerance is configured,
+// so signatures can never be replayed forever
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// NewWebhookVerifier returns a verifier for the secrets by provider name that
+// rejects webhooks signed more than tolerance away from now
+func NewWebhookVerifier(secrets map[string]string, tolerance time.Duration) *WebhookVerifier {
+	if tolerance <= 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+
+	verifier := &WebhookVerifier{secrets: map[string]string{}, tolerance: tolerance}
+	for provider, secret := range secrets {
+		verifier.secrets[strings.ToLower(provider)] = secret
+	}
+	return verifier
+}
+
+// Accepts reports whether webhooks of the provider can be verified
+func (v *WebhookVerifier) Accepts(provider string) bool {
+	return v.secrets[strings.ToLower(provider)] != ""
+}
+
+// Verify checks the hex encoded HMAC-SHA256 signature of the payload. The
+// signed message is the unix timestamp, a dot and the payload.
+func (v *WebhookVerifier) Verify(provider, timestamp, signature string, payload []byte, now time.Time) error {
+	secret := v.secrets[strings.ToLower(provider)]
+	if secret == "" {
+		return fmt.Errorf("%w: no secret for provider %s", ErrUnknownProvider, provider)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+
+	signedAt := time.Unix(seconds, 0)
+	if now.Sub(signedAt) > v.tolerance || signedAt.Sub(now) > v.tolerance {
+		return fmt.Errorf("%w: timestamp outside the tolerance", ErrInvalidSignature)
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, Sign(secret, timestamp, payload)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 of the timestamp and payload with secret
+func Sign(secret, timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
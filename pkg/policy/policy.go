@@ -0,0 +1,133 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+)
+
+// Names of the rules an order can violate
+const (
+	RuleMaxOpenOrders = "max_open_orders"
+	RuleMaxOrderValue = "max_order_value"
+)
+
+// Scopes a limit can come from, from the most general to the most specific
+const (
+	ScopeDefault  = "default"
+	ScopeCountry  = "country"
+	ScopeCustomer = "customer"
+)
+
+// Limits caps what a customer may order. A nil MaxOpenOrders or a currency
+// missing from MaxOrderValue inherits the limit of the broader scope, and a
+// limit of zero lifts it.
+type Limits struct {
+	MaxOpenOrders *int
+	MaxOrderValue map[string]int64
+}
+
+// Violation names the rule an order breaks and the scope the limit came from
+type Violation struct {
+	Rule    string `json:"rule"`
+	Scope   string `json:"scope"`
+	Message string `json:"message"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("order violates rule '%s' (%s limit): %s", v.Rule, v.Scope, v.Message)
+}
+
+// Engine resolves the limits that apply to a customer from the default
+// policy, the customer's country and the customer's own override
+type Engine struct {
+	defaults  Limits
+	countries map[string]Limits
+}
+
+// NewEngine returns the engine for the configured order policy
+func NewEngine(conf *config.OrderPolicy) *Engine {
+	engine := &Engine{countries: map[string]Limits{}}
+	if conf == nil {
+		return engine
+	}
+
+	engine.defaults = fromConfig(conf.OrderLimits)
+	for country, limits := range conf.Countries {
+		engine.countries[strings.ToLower(country)] = fromConfig(limits)
+	}
+
+	return engine
+}
+
+func fromConfig(limits config.OrderLimits) Limits {
+	maxOrderValue := make(map[string]int64, len(limits.MaxOrderValue))
+	for currency, amount := range limits.MaxOrderValue {
+		maxOrderValue[strings.ToUpper(currency)] = amount
+	}
+	return Limits{MaxOpenOrders: limits.MaxOpenOrders, MaxOrderValue: maxOrderValue}
+}
+
+// Policy is the set of limits in effect for one customer
+type Policy struct {
+	maxOpenOrders      int
+	maxOpenOrdersScope string
+	maxOrderValue      map[string]int64
+	maxOrderValueScope map[string]string
+}
+
+// For returns the policy of a customer in the given country. override holds
+// the customer's own limits and may be nil.
+func (e *Engine) For(country string, override *Limits) Policy {
+	policy := Policy{maxOrderValue: map[string]int64{}, maxOrderValueScope: map[string]string{}}
+
+	policy.apply(e.defaults, ScopeDefault)
+	if limits, ok := e.countries[strings.ToLower(country)]; ok {
+		policy.apply(limits, ScopeCountry+" "+country)
+	}
+	if override != nil {
+		policy.apply(*override, ScopeCustomer)
+	}
+
+	return policy
+}
+
+func (p *Policy) apply(limits Limits, scope string) {
+	if limits.MaxOpenOrders != nil {
+		p.maxOpenOrders = *limits.MaxOpenOrders
+		p.maxOpenOrdersScope = scope
+	}
+	for currency, amount := range limits.MaxOrderValue {
+		p.maxOrderValue[strings.ToUpper(currency)] = amount
+		p.maxOrderValueScope[strings.ToUpper(currency)] = scope
+	}
+}
+
+// CheckOpenOrders reports whether a customer with open orders may place another one
+func (p Policy) CheckOpenOrders(open int64) *Violation {
+	if p.maxOpenOrders <= 0 || open < int64(p.maxOpenOrders) {
+		return nil
+	}
+
+	return &Violation{
+		Rule:    RuleMaxOpenOrders,
+		Scope:   p.maxOpenOrdersScope,
+		Message: fmt.Sprintf("customer has %d open orders, at most %d allowed", open, p.maxOpenOrders),
+	}
+}
+
+// CheckOrderValue reports whether an order of the given total may be placed
+func (p Policy) CheckOrderValue(total money.Money) *Violation {
+	limit := p.maxOrderValue[total.Currency]
+	if limit <= 0 || total.Amount <= limit {
+		return nil
+	}
+
+	return &Violation{
+		Rule:    RuleMaxOrderValue,
+		Scope:   p.maxOrderValueScope[total.Currency],
+		Message: fmt.Sprintf("order total %v exceeds %v", total, money.New(limit, total.Currency)),
+	}
+}
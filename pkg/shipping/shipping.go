@@ -0,0 +1,93 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+)
+
+var ErrNotShippable = errors.New("order cannot be shipped")
+
+// anyCountry in a zone's countries matches the countries of no other zone
+const anyCountry = "*"
+
+// Result is the shipping cost of an order and the zone it was priced in
+type Result struct {
+	Zone string
+	Cost money.Money
+}
+
+// Engine prices shipping from the configured zones and weight brackets
+type Engine struct {
+	zones    map[string]config.ShippingZone
+	fallback *config.ShippingZone
+	hasZones bool
+}
+
+// NewEngine returns the engine for the configured shipping zones
+func NewEngine(conf *config.Shipping) *Engine {
+	engine := &Engine{zones: map[string]config.ShippingZone{}}
+	if conf == nil {
+		return engine
+	}
+
+	for _, zone := range conf.Zones {
+		zone.Currency = strings.ToUpper(zone.Currency)
+		zone.Rates = append([]config.ShippingRate(nil), zone.Rates...)
+		sort.Slice(zone.Rates, func(i, j int) bool { return zone.Rates[i].MaxWeightGrams < zone.Rates[j].MaxWeightGrams })
+
+		for _, country := range zone.Countries {
+			if country == anyCountry {
+				fallback := zone
+				engine.fallback = &fallback
+				continue
+			}
+			engine.zones[strings.ToLower(country)] = zone
+		}
+		engine.hasZones = true
+	}
+
+	return engine
+}
+
+// Calculate returns the cost of shipping an order of the given weight to the
+// country, in the currency of the order. Shipping is free when no zones are
+// configured.
+func (e *Engine) Calculate(country string, weightGrams int, currency string) (Result, error) {
+	if !e.hasZones {
+		return Result{Cost: money.Zero(currency)}, nil
+	}
+
+	zone, ok := e.zones[strings.ToLower(country)]
+	if !ok {
+		if e.fallback == nil {
+			return Result{}, fmt.Errorf("%w: no shipping to %s", ErrNotShippable, country)
+		}
+		zone = *e.fallback
+	}
+
+	if zone.Currency != currency {
+		return Result{}, fmt.Errorf("%w: shipping zone %s is priced in %s, not %s", ErrNotShippable, zone.Name, zone.Currency, currency)
+	}
+	if len(zone.Rates) == 0 {
+		return Result{}, fmt.Errorf("%w: shipping zone %s has no rates", ErrNotShippable, zone.Name)
+	}
+
+	for _, rate := range zone.Rates {
+		if weightGrams <= rate.MaxWeightGrams {
+			return Result{Zone: zone.Name, Cost: money.New(rate.Price, currency)}, nil
+		}
+	}
+
+	heaviest := zone.Rates[len(zone.Rates)-1]
+	if zone.ExtraPerKg <= 0 {
+		return Result{}, fmt.Errorf("%w: %d g is over the %d g limit of shipping zone %s", ErrNotShippable, weightGrams, heaviest.MaxWeightGrams, zone.Name)
+	}
+
+	extraKg := (weightGrams - heaviest.MaxWeightGrams + 999) / 1000
+	return Result{Zone: zone.Name, Cost: money.New(heaviest.Price+int64(extraKg)*zone.ExtraPerKg, currency)}, nil
+}
@@ -0,0 +1,120 @@
+package tax
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+)
+
+// Rate is a tax rate in basis points, e.g. 1800 for 18%
+type Rate struct {
+	Name        string
+	BasisPoints int64
+}
+
+func (r Rate) String() string {
+	return fmt.Sprintf("%s %d.%02d%%", r.Name, r.BasisPoints/100, r.BasisPoints%100)
+}
+
+// Line is an amount to tax, after discounts, and the category of its product
+type Line struct {
+	Category string
+	Amount   money.Money
+}
+
+// Result is the tax charged at one rate over all the lines taxed at it
+type Result struct {
+	Rate    Rate
+	Taxable money.Money
+	Amount  money.Money
+}
+
+type countryRates struct {
+	standard   Rate
+	categories map[string]Rate
+}
+
+// Engine calculates the tax of an order from the rates configured for the
+// country an order is shipped to and the categories of the ordered products
+type Engine struct {
+	inclusive bool
+	countries map[string]countryRates
+}
+
+// NewEngine returns the engine for the configured tax rates
+func NewEngine(conf *config.Tax) *Engine {
+	engine := &Engine{countries: map[string]countryRates{}}
+	if conf == nil {
+		return engine
+	}
+
+	engine.inclusive = conf.PricesIncludeTax
+	for country, countryConf := range conf.Countries {
+		rates := countryRates{
+			standard:   Rate{Name: countryConf.Name, BasisPoints: basisPoints(countryConf.Rate)},
+			categories: map[string]Rate{},
+		}
+		for category, rate := range countryConf.Categories {
+			rates.categories[strings.ToLower(category)] = Rate{Name: countryConf.Name, BasisPoints: basisPoints(rate)}
+		}
+		engine.countries[strings.ToLower(country)] = rates
+	}
+
+	return engine
+}
+
+func basisPoints(percent float64) int64 {
+	return int64(math.Round(percent * 100))
+}
+
+// PricesIncludeTax reports whether prices already contain tax, in which case
+// tax is reported but not added to the order total
+func (e *Engine) PricesIncludeTax() bool {
+	return e.inclusive
+}
+
+// Calculate returns the tax of the lines shipped to the given country,
+// one result per rate. Countries without configured rates are not taxed.
+func (e *Engine) Calculate(country string, lines []Line) []Result {
+	rates, ok := e.countries[strings.ToLower(country)]
+	if !ok {
+		return nil
+	}
+
+	taxable := map[Rate]money.Money{}
+	for _, line := range lines {
+		rate, ok := rates.categories[strings.ToLower(line.Category)]
+		if !ok {
+			rate = rates.standard
+		}
+
+		if current, ok := taxable[rate]; ok {
+			line.Amount, _ = current.Add(line.Amount)
+		}
+		taxable[rate] = line.Amount
+	}
+
+	var results []Result
+	for rate, amount := range taxable {
+		if rate.BasisPoints == 0 {
+			continue
+		}
+
+		// Rounded half up to the minor unit, once per rate
+		var tax int64
+		if e.inclusive {
+			tax = (amount.Amount*rate.BasisPoints*2 + 10000 + rate.BasisPoints) / (2 * (10000 + rate.BasisPoints))
+		} else {
+			tax = (amount.Amount*rate.BasisPoints + 5000) / 10000
+		}
+
+		results = append(results, Result{Rate: rate, Taxable: amount, Amount: money.New(tax, amount.Currency)})
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Rate.BasisPoints > results[j].Rate.BasisPoints })
+	return results
+}
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenAddress is returned for subscriber URLs pointing into the
+// network the service runs in
+var ErrForbiddenAddress = errors.New("webhook URL must resolve to a public address")
+
+// URLGuard keeps deliveries from reaching the service's own network, e.g.
+// the cloud metadata endpoint or the database, on behalf of whoever
+// registered the subscription
+type URLGuard struct {
+	allowPrivate bool
+	resolver     *net.Resolver
+}
+
+// NewURLGuard returns a guard for subscriber URLs. allowPrivate lets them
+// point at loopback, link-local and private addresses, for local receivers.
+func NewURLGuard(allowPrivate bool) *URLGuard {
+	return &URLGuard{allowPrivate: allowPrivate, resolver: net.DefaultResolver}
+}
+
+// Check rejects URLs that are not http or https or whose host resolves to a
+// forbidden address. The address is checked again whenever a delivery dials
+// it, since the host may resolve elsewhere by then.
+func (g *URLGuard) Check(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("webhook URL must use http or https, not %q", parsed.Scheme)
+	}
+
+	host := parsed.Hostname()
+	if host == "" {
+		return errors.New("webhook URL must have a host")
+	}
+
+	if g.allowPrivate {
+		return nil
+	}
+
+	addresses, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("webhook URL host %q cannot be resolved: %w", host, err)
+	}
+
+	for _, address := range addresses {
+		if !publicAddress(address.IP) {
+			return fmt.Errorf("%w, %v resolves to %v", ErrForbiddenAddress, host, address.IP)
+		}
+	}
+
+	return nil
+}
+
+// Control is a net.Dialer Control function refusing connections to forbidden
+// addresses. It sees the address actually dialed, after name resolution and
+// for every redirect.
+func (g *URLGuard) Control(network, address string, c syscall.RawConn) error {
+	if g.allowPrivate {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !publicAddress(ip) {
+		return fmt.Errorf("%w, not %v", ErrForbiddenAddress, host)
+	}
+
+	return nil
+}
+
+func publicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+)
+
+// Headers sent with every delivery
+const (
+	EventIDHeader   = "X-Webhook-Id"
+	EventTypeHeader = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Outcome is what happened to a delivery after an attempt
+type Outcome string
+
+const (
+	Delivered Outcome = "delivered"
+	// Retry deliveries are attempted again at NextAttemptAt
+	Retry Outcome = "retry"
+	// Dead deliveries ran out of attempts and are not retried
+	Dead Outcome = "dead"
+)
+
+// Delivery is one event to send to one subscriber
+type Delivery struct {
+	ID        string
+	URL       string
+	Secret    string
+	EventID   string
+	EventType string
+	Payload   []byte
+	// Attempts made before this one
+	Attempts int
+}
+
+// Attempt is the result of sending a delivery once
+type Attempt struct {
+	Outcome       Outcome
+	StatusCode    int
+	Error         string
+	Duration      time.Duration
+	AttemptedAt   time.Time
+	NextAttemptAt time.Time
+}
+
+// Store keeps the delivery queue. Claimed deliveries are not handed out
+// again until the lease ends, so several dispatchers can share a queue.
+type Store interface {
+	ClaimDeliveries(now time.Time, limit int, lease time.Duration) ([]Delivery, error)
+	RecordAttempt(delivery Delivery, attempt Attempt) error
+}
+
+// Dispatcher sends due deliveries of the store to their subscribers,
+// retrying failed ones with exponential backoff
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	conf   config.Webhooks
+	now    func() time.Time
+}
+
+// NewDispatcher returns a dispatcher for the store. Its client refuses to
+// connect to the addresses a subscription could not be created for.
+func NewDispatcher(store Store, conf config.Webhooks) *Dispatcher {
+	dialer := &net.Dialer{Timeout: conf.Timeout, Control: NewURLGuard(conf.AllowPrivateNetworks).Control}
+
+	// A proxy would be dialed instead of the subscriber, hiding its address
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: conf.Timeout, Transport: transport},
+		conf:   conf,
+		now:    time.Now,
+	}
+}
+
+// Run dispatches deliveries every poll interval until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.conf.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchDue(); err != nil {
+			logger.Log.Error("Error dispatching webhooks: ", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends one batch of due deliveries
+func (d *Dispatcher) DispatchDue() error {
+	// The batch stays leased for as long as sending every delivery of it can
+	// take, plus one timeout to spare for the store
+	lease := time.Duration(d.conf.BatchSize+1) * d.conf.Timeout
+	leaseEnds := d.now().Add(lease)
+
+	deliveries, err := d.store.ClaimDeliveries(d.now(), d.conf.BatchSize, lease)
+	if err != nil {
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		// A delivery that could still be sending when the lease ends may be
+		// claimed and sent by another dispatcher too, so it is left for later
+		if d.now().Add(d.conf.Timeout).After(leaseEnds) {
+			logger.Log.Warnf("Webhook lease ends before delivery %v can be sent, leaving the rest of the batch", delivery.ID)
+			break
+		}
+
+		attempt := d.send(delivery)
+		if err := d.store.RecordAttempt(delivery, attempt); err != nil {
+			logger.Log.Errorf("Could not record attempt of webhook delivery %v: %v", delivery.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) send(delivery Delivery) Attempt {
+	attempt := Attempt{AttemptedAt: d.now()}
+	timestamp := strconv.FormatInt(attempt.AttemptedAt.Unix(), 10)
+
+	err := func() error {
+		req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(EventIDHeader, delivery.EventID)
+		req.Header.Set(EventTypeHeader, delivery.EventType)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(Sign(delivery.Secret, timestamp, delivery.Payload)))
+
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+		attempt.StatusCode = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("subscriber responded %s", resp.Status)
+		}
+		return nil
+	}()
+	attempt.Duration = d.now().Sub(attempt.AttemptedAt)
+
+	switch {
+	case err == nil:
+		attempt.Outcome = Delivered
+	case delivery.Attempts+1 >= d.conf.MaxAttempts:
+		attempt.Outcome = Dead
+		attempt.Error = err.Error()
+	default:
+		attempt.Outcome = Retry
+		attempt.Error = err.Error()
+		attempt.NextAttemptAt = attempt.AttemptedAt.Add(Backoff(delivery.Attempts+1, d.conf.RetryBaseDelay, d.conf.RetryMaxDelay))
+	}
+
+	return attempt
+}
+
+// Backoff returns the delay before retrying after the given number of
+// attempts: base, doubling with every attempt, at most max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// Sign returns the HMAC-SHA256 of the timestamp and payload with secret.
+// Subscribers recompute it over the timestamp header, a dot and the raw body.
+func Sign(secret, timestamp string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetCustomerAddresses returns every address of the customer, defaults first
+func (c customerRepository) GetCustomerAddresses(ctx context.Context, customerID string) ([]entities.Address, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	if _, errs := c.GetCustomerByID(ctx, customerID); errs != nil {
+		return nil, errs
+	}
+
+	addresses := []entities.Address{}
+	err := c.db.GetDb().WithContext(ctx).Debug().
+		Where("customer_id = ?", customerID).
+		Order("is_default_shipping DESC, is_default_billing DESC, created_at, id").
+		Find(&addresses).Error
+	if err != nil {
+		logger.Log.Error("Error fetching addresses: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return addresses, nil
+}
+
+// CreateAddress adds an address to the customer. An address created as a
+// default replaces the customer's previous default.
+func (c customerRepository) CreateAddress(ctx context.Context, customerID string, address *entities.Address) (*entities.Address, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	if _, errs := c.GetCustomerByID(ctx, customerID); errs != nil {
+		return nil, errs
+	}
+
+	address.CustomerID = uuid.MustParse(customerID)
+
+	err := c.db.WithTx(ctx, func(tx *gorm.DB) error {
+		if err := clearDefaultAddresses(tx, address); err != nil {
+			return err
+		}
+		return tx.Debug().Create(address).Error
+	})
+	if err != nil {
+		return nil, addressWriteError(err, "Could not create address.")
+	}
+
+	logger.Log.Infof("Address %v added to customer %v", address.ID, customerID)
+	return address, nil
+}
+
+// UpdateAddress replaces the details of an address of the customer
+func (c customerRepository) UpdateAddress(ctx context.Context, customerID, addressID string, address *entities.Address) (*entities.Address, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	existing, errs := c.getAddress(ctx, customerID, addressID)
+	if errs != nil {
+		return nil, errs
+	}
+
+	address.ID = existing.ID
+	address.CustomerID = existing.CustomerID
+	address.CreatedAt = existing.CreatedAt
+
+	err := c.db.WithTx(ctx, func(tx *gorm.DB) error {
+		if err := clearDefaultAddresses(tx, address); err != nil {
+			return err
+		}
+		return tx.Debug().Model(address).
+			Select("recipient", "line1", "line2", "city", "state", "postal_code", "country", "is_default_billing", "is_default_shipping").
+			Updates(address).Error
+	})
+	if err != nil {
+		return nil, addressWriteError(err, "Could not update address.")
+	}
+
+	logger.Log.Infof("Address %v of customer %v updated", addressID, customerID)
+	return c.getAddress(ctx, customerID, addressID)
+}
+
+// DeleteAddress removes an address of the customer. Orders keep the copy of
+// the address they were shipped to.
+func (c customerRepository) DeleteAddress(ctx context.Context, customerID, addressID string) errorPkg.CustomErrors {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	result := c.db.GetDb().WithContext(ctx).Debug().Where("customer_id = ?", customerID).Delete(&entities.Address{}, "id = ?", addressID)
+	if result.Error != nil {
+		logger.Log.Error("Could not delete address: ", result.Error)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete address.")
+	}
+
+	if result.RowsAffected == 0 {
+		logger.Log.Warnf("Address %v of customer %v not found", addressID, customerID)
+		return errorPkg.CustomErrorHandle(http.StatusNotFound, "Address not found.")
+	}
+
+	return nil
+}
+
+func (c customerRepository) getAddress(ctx context.Context, customerID, addressID string) (*entities.Address, errorPkg.CustomErrors) {
+	var address entities.Address
+	err := c.db.GetDb().WithContext(ctx).Debug().Where("customer_id = ?", customerID).First(&address, "id = ?", addressID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Address %v of customer %v not found", addressID, customerID)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Address not found.")
+		}
+
+		logger.Log.Error("Error fetching address: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return &address, nil
+}
+
+// addressWriteError maps a failed address write to the error the API
+// reports. A unique violation means another request took the default in the
+// meantime, which the client can retry.
+func addressWriteError(err error, message string) errorPkg.CustomErrors {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		logger.Log.Warnf("Default address changed concurrently: %v", err)
+		return errorPkg.CustomErrorHandle(http.StatusConflict, "The customer's default address was changed by another request.")
+	}
+
+	logger.Log.Errorf("%v %v", message, err)
+	return errorPkg.CustomErrorHandle(http.StatusInternalServerError, message)
+}
+
+// clearDefaultAddresses unsets the default flags the address takes over on
+// the customer's other addresses. The customer row is locked first so that
+// concurrent requests taking over a default are serialised instead of both
+// clearing the old default and colliding on the unique default index.
+func clearDefaultAddresses(tx *gorm.DB, address *entities.Address) error {
+	if !address.IsDefaultBilling && !address.IsDefaultShipping {
+		return nil
+	}
+
+	var customer entities.Customer
+	if err := tx.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("id = ?", address.CustomerID).First(&customer).Error; err != nil {
+		return err
+	}
+
+	others := tx.Debug().Model(&entities.Address{}).Where("customer_id = ?", address.CustomerID)
+	if address.ID != uuid.Nil {
+		others = others.Where("id <> ?", address.ID)
+	}
+
+	if address.IsDefaultBilling {
+		if err := others.Session(&gorm.Session{}).Where("is_default_billing").Update("is_default_billing", false).Error; err != nil {
+			return err
+		}
+	}
+	if address.IsDefaultShipping {
+		if err := others.Session(&gorm.Session{}).Where("is_default_shipping").Update("is_default_shipping", false).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type couponRepository struct {
+	db database.Database
+}
+
+func NewCouponRepository(db database.Database) CouponHandler {
+	return &couponRepository{db: db}
+}
+
+// couponSorts lists the columns coupons can be sorted on
+var couponSorts = timestampSorts(map[string]sortOption[entities.Coupon]{
+	"code": {Expr: "code", Value: func(c entities.Coupon) string { return c.Code }},
+}, func(c entities.Coupon) entities.BaseModel { return c.BaseModel })
+
+// GetAllCoupons returns one page of the coupons matching the query
+func (cr couponRepository) GetAllCoupons(ctx context.Context, query entities.CouponListQuery) (*entities.Page[entities.Coupon], errorPkg.CustomErrors) {
+	if cr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	db := filterTimestamps(cr.db.GetDb().WithContext(ctx).Debug().Model(&entities.Coupon{}), query.TimestampFilter)
+
+	if query.Type != "" {
+		db = db.Where("type = ?", query.Type)
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "code"
+	}
+
+	page, err := keysetPage(db, pageRequest{Limit: query.Limit, Cursor: query.Cursor, Sort: sort}, couponSorts,
+		func(c entities.Coupon) string { return c.ID.String() })
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			logger.Log.Warn("Invalid cursor for listing coupons")
+			return nil, errorPkg.CustomErrorHandle(http.StatusBadRequest, err.Error())
+		}
+
+		logger.Log.Error("Error fetching coupons: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Log.Infof("Coupons fetched successfully!. Returning %d of %d coupons", len(page.Items), page.TotalCount)
+	return page, nil
+}
+
+// GetCouponByID retrieves the coupon from database by provided Id
+func (cr couponRepository) GetCouponByID(ctx context.Context, id string) (*entities.Coupon, errorPkg.CustomErrors) {
+	if cr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var coupon *entities.Coupon
+	if err := cr.db.GetDb().WithContext(ctx).Debug().Where("id=?", id).First(&coupon).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Log.Warnf("Coupon with id %v not found.", id)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Coupon not found.")
+		}
+
+		logger.Log.Error("Error fetching coupon: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return coupon, nil
+}
+
+// CreateCoupon adds a new coupon, whose code must not be in use
+func (cr couponRepository) CreateCoupon(ctx context.Context, coupon *entities.Coupon) (*entities.Coupon, errorPkg.CustomErrors) {
+	if cr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	if err := cr.db.GetDb().WithContext(ctx).Debug().Create(coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			logger.Log.Warnf("Coupon code %v already exists", coupon.Code)
+			return nil, errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Coupon code '%v' already exists", coupon.Code))
+		}
+
+		logger.Log.Error("Could not create coupon: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create coupon.")
+	}
+
+	logger.Log.Infof("Coupon created successfully with ID: %v", coupon.ID)
+	return coupon, nil
+}
+
+// DeleteCoupon soft deletes the coupon so orders that redeemed it keep their discount
+func (cr couponRepository) DeleteCoupon(ctx context.Context, id string) errorPkg.CustomErrors {
+	if cr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	tx := cr.db.GetDb().WithContext(ctx).Debug().Where("id=?", id).Delete(&entities.Coupon{})
+	if tx.Error != nil {
+		logger.Log.Error("Could not delete coupon: ", tx.Error)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete coupon.")
+	}
+
+	if tx.RowsAffected == 0 {
+		logger.Log.Warnf("Coupon with id %v not found.", id)
+		return errorPkg.CustomErrorHandle(http.StatusNotFound, "Coupon not found.")
+	}
+
+	logger.Log.Infof("Coupon deleted successfully with ID: %v", id)
+	return nil
+}
+
+// redeemCoupon locks the coupon with the given code, checks that the customer
+// may still use it and returns the discount it gives on the order lines.
+// Redemptions of cancelled orders do not count towards the usage limits. A
+// read only unit of work checks the coupon without locking it.
+func redeemCoupon(db *gorm.DB, code string, customerID uuid.UUID, lines []entities.CouponLine, readOnly bool) (*entities.OrderDiscount, *entities.Coupon, errorPkg.CustomErrors) {
+	var coupon entities.Coupon
+	err := forUpdate(db.Debug(), readOnly).Where("code = ?", entities.NormalizeCouponCode(code)).First(&coupon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Unknown coupon code %v", code)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("Unknown coupon code '%v'", code))
+		}
+
+		logger.Log.Error("Error fetching coupon: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	if err := coupon.CheckValidAt(time.Now()); err != nil {
+		logger.Log.Warn("Coupon not valid: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	redemptions := func(scope func(*gorm.DB) *gorm.DB) (int64, error) {
+		var count int64
+		err := db.Debug().Model(&entities.OrderDiscount{}).
+			Joins("JOIN orders ON orders.id = order_discounts.order_id AND orders.deleted_at IS NULL").
+			Where("order_discounts.coupon_id = ? AND orders.status <> ?", coupon.ID, entities.Cancelled).
+			Scopes(scope).Count(&count).Error
+		return count, err
+	}
+
+	if coupon.MaxRedemptions != nil {
+		count, err := redemptions(func(db *gorm.DB) *gorm.DB { return db })
+		if err != nil {
+			logger.Log.Error("Error counting coupon redemptions: ", err)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+		if count >= int64(*coupon.MaxRedemptions) {
+			logger.Log.Warnf("Coupon %v fully redeemed", coupon.Code)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("Coupon '%v' has been fully redeemed", coupon.Code))
+		}
+	}
+
+	if coupon.MaxRedemptionsPerCustomer != nil {
+		count, err := redemptions(func(db *gorm.DB) *gorm.DB { return db.Where("orders.customer_id = ?", customerID) })
+		if err != nil {
+			logger.Log.Error("Error counting coupon redemptions: ", err)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+		if count >= int64(*coupon.MaxRedemptionsPerCustomer) {
+			logger.Log.Warnf("Coupon %v already redeemed by customer %v", coupon.Code, customerID)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("Coupon '%v' has already been redeemed the maximum number of times", coupon.Code))
+		}
+	}
+
+	amount, err := coupon.Discount(lines)
+	if err != nil {
+		logger.Log.Warn("Coupon not applicable: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	return &entities.OrderDiscount{CouponID: coupon.ID, Code: coupon.Code, Amount: amount}, &coupon, nil
+}
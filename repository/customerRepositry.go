@@ -1,62 +1,104 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/shipping"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/tax"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/google/uuid"
 )
 
 type customerRepository struct {
-	db database.Database
+	db          database.Database
+	orderPolicy *policy.Engine
+	taxes       *tax.Engine
+	shipping    *shipping.Engine
+	payments    *payment.Registry
 }
 
-func NewCustomerRepository(db database.Database) CustomerHandler {
-	return &customerRepository{db: db}
+func NewCustomerRepository(db database.Database, orderPolicy *policy.Engine, taxes *tax.Engine, shipping *shipping.Engine, payments *payment.Registry) CustomerHandler {
+	return &customerRepository{db: db, orderPolicy: orderPolicy, taxes: taxes, shipping: shipping, payments: payments}
 }
 
-// GetAllCustomers returns the list of all customers
-func (c customerRepository) GetAllCustomers() ([]entities.Customer, errorPkg.CustomErrors) {
+// customerSorts lists the columns customers can be sorted on
+var customerSorts = timestampSorts(map[string]sortOption[entities.Customer]{
+	"name":    {Expr: "name", Value: func(c entities.Customer) string { return c.Name }},
+	"email":   {Expr: "email", Value: func(c entities.Customer) string { return c.Email }},
+	"country": {Expr: "COALESCE(country, '')", Value: func(c entities.Customer) string { return c.Country }},
+}, func(c entities.Customer) entities.BaseModel { return c.BaseModel })
+
+// GetAllCustomers returns one page of the customers matching the query
+func (c customerRepository) GetAllCustomers(ctx context.Context, query entities.CustomerListQuery) (*entities.Page[entities.Customer], errorPkg.CustomErrors) {
 	if c.db == nil {
 		logger.Log.Warnf("Database connection not available.")
 		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
 	}
 
-	db := c.db.GetDb().Begin()
+	db := filterTimestamps(c.db.GetDb().WithContext(ctx).Debug().Model(&entities.Customer{}), query.TimestampFilter)
 
-	var customers []entities.Customer
-	if err := db.Debug().Model(&entities.Customer{}).Find(&customers).Error; err != nil {
-		logger.Log.Error("Error fetching customers: ", err)
-		return nil, errorPkg.HandleError(db, err)
+	if query.Country != "" {
+		db = db.Where("country = ?", query.Country)
+	}
+	if query.NamePrefix != "" {
+		db = db.Where("name ILIKE ?", escapeLike(query.NamePrefix)+"%")
+	}
+	if query.Email != "" {
+		db = db.Where("lower(email) = lower(?)", query.Email)
 	}
 
-	if len(customers) == 0 {
-		return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "no customer available")
+	sort := query.Sort
+	if sort == "" {
+		sort = "name"
 	}
 
-	logger.Log.Infof("Customers fetched successfully!. Total number of customers are: %d", len(customers))
-	return customers, nil
+	page, err := keysetPage(db, pageRequest{Limit: query.Limit, Cursor: query.Cursor, Sort: sort}, customerSorts,
+		func(c entities.Customer) string { return c.ID.String() })
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			logger.Log.Warn("Invalid cursor for listing customers")
+			return nil, errorPkg.CustomErrorHandle(http.StatusBadRequest, err.Error())
+		}
+
+		logger.Log.Error("Error fetching customers: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Log.Infof("Customers fetched successfully!. Returning %d of %d customers", len(page.Items), page.TotalCount)
+	return page, nil
 }
 
 // GetCustomerByID retrives the customer from database by provided Id
-func (c customerRepository) GetCustomerByID(id string) (*entities.Customer, errorPkg.CustomErrors) {
+func (c customerRepository) GetCustomerByID(ctx context.Context, id string) (*entities.Customer, errorPkg.CustomErrors) {
 	if c.db == nil {
 		logger.Log.Warnf("Database connection not available.")
 		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
 	}
 
-	db := c.db.GetDb().Begin()
+	db := c.db.GetDb().WithContext(ctx)
 
 	var customer *entities.Customer
-	tx := db.Debug().Model(&entities.Customer{}).Find(&customer).Where("id=?", id)
+	tx := db.Debug().Model(&entities.Customer{}).Where("id=?", id).First(&customer)
 	if tx.Error != nil {
+		if tx.Error == gorm.ErrRecordNotFound {
+			logger.Log.Warnf("Customer with id %v not found.", id)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Customer not found.")
+		}
+
 		logger.Log.Error("Error fetching customer: ", tx.Error)
 		return nil, errorPkg.HandleError(tx, tx.Error)
 	}
@@ -66,99 +108,629 @@ func (c customerRepository) GetCustomerByID(id string) (*entities.Customer, erro
 
 }
 
+// CreateCustomer registers a new customer with a unique email
+func (c customerRepository) CreateCustomer(ctx context.Context, customer *entities.Customer) (*entities.Customer, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		if errs := c.checkEmailAvailable(db, customer.Email, uuid.Nil); errs != nil {
+			return errs
+		}
+
+		if err := db.Debug().Create(customer).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				logger.Log.Warnf("Email %v is already registered.", customer.Email)
+				return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Email '%v' is already registered", customer.Email))
+			}
+
+			logger.Log.Error("Could not create customer: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create customer.")
+		}
+
+		if err := appendOutboxEvent(db, entities.AggregateCustomer, customer.ID, entities.EventCustomerCreated, customer); err != nil {
+			logger.Log.Error("Could not record customer event: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create customer.")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	logger.Log.Infof("Customer created successfully with ID: %v", customer.ID)
+	return customer, nil
+}
+
+// UpdateCustomer replaces the name, email and country of an existing customer
+func (c customerRepository) UpdateCustomer(ctx context.Context, id string, customer *entities.Customer) (*entities.Customer, errorPkg.CustomErrors) {
+	existing, errs := c.GetCustomerByID(ctx, id)
+	if errs != nil {
+		return nil, errs
+	}
+
+	existing.Name = customer.Name
+	existing.Email = customer.Email
+	existing.Country = customer.Country
+
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		if errs := c.checkEmailAvailable(db, customer.Email, existing.ID); errs != nil {
+			return errs
+		}
+
+		err := db.Debug().Model(existing).Select("name", "email", "country").Updates(existing).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				logger.Log.Warnf("Email %v is already registered.", customer.Email)
+				return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Email '%v' is already registered", customer.Email))
+			}
+
+			logger.Log.Error("Could not update customer: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update customer.")
+		}
+
+		if err := appendOutboxEvent(db, entities.AggregateCustomer, existing.ID, entities.EventCustomerUpdated, existing); err != nil {
+			logger.Log.Error("Could not record customer event: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update customer.")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	logger.Log.Infof("Customer updated successfully with ID: %v", existing.ID)
+	return existing, nil
+}
+
+// DeleteCustomer removes a customer that has no open orders
+func (c customerRepository) DeleteCustomer(ctx context.Context, id string) errorPkg.CustomErrors {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		// Lock the customer so no order can be placed while we check
+		var customer entities.Customer
+		if err := db.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", id).First(&customer).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				logger.Log.Warnf("Customer with id %v not found.", id)
+				return errorPkg.CustomErrorHandle(http.StatusNotFound, "Customer not found.")
+			}
+
+			logger.Log.Error("Error fetching customer: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		var openOrders int64
+		err := db.Debug().Model(&entities.Order{}).Where("customer_id = ? AND status IN ?", id, entities.OpenOrderStatuses()).Count(&openOrders).Error
+		if err != nil {
+			logger.Log.Error("Error counting open orders: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		if openOrders > 0 {
+			logger.Log.Warnf("Customer %v still has %d open orders", id, openOrders)
+			return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Customer with id '%v' has %d open orders", id, openOrders))
+		}
+
+		if err := db.Debug().Delete(&customer).Error; err != nil {
+			logger.Log.Error("Could not delete customer: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete customer.")
+		}
+
+		if err := appendOutboxEvent(db, entities.AggregateCustomer, customer.ID, entities.EventCustomerDeleted, customer); err != nil {
+			logger.Log.Error("Could not record customer event: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete customer.")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return txError(err)
+	}
+
+	logger.Log.Infof("Customer deleted successfully with ID: %v", id)
+	return nil
+}
+
+// checkEmailAvailable makes sure no other customer is registered with the email
+func (c customerRepository) checkEmailAvailable(db *gorm.DB, email string, customerID uuid.UUID) errorPkg.CustomErrors {
+	var count int64
+	err := db.Debug().Model(&entities.Customer{}).Where("lower(email) = lower(?) AND id <> ?", email, customerID).Count(&count).Error
+	if err != nil {
+		logger.Log.Error("Error checking customer email: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	if count > 0 {
+		logger.Log.Warnf("Email %v is already registered.", email)
+		return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Email '%v' is already registered", email))
+	}
+
+	return nil
+}
+
 // CreateOrder
-func (c customerRepository) CreateOrder(customerID string, productIds []string) (*entities.Order, errorPkg.CustomErrors) {
+func (c customerRepository) CreateOrder(ctx context.Context, request entities.OrderRequest) (*entities.Order, errorPkg.CustomErrors) {
 
 	if c.db == nil {
 		logger.Log.Warnf("Database connection not available.")
 		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
 	}
 
-	db := c.db.GetDb().Begin()
+	var order *entities.Order
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		prepared, violations, errs := c.prepareOrder(db, request, false)
+		if errs != nil {
+			return errs
+		}
 
-	var customer entities.Customer
-	if err := db.Debug().Where("id = ?", customerID).Find(&customer).Error; err != nil {
-		logger.Log.Warnf("Customer with id %v not found.", customerID)
-		return nil, errorPkg.HandleError(db, err)
+		if len(violations) > 0 {
+			logger.Log.Warnf("Order policy violated for customer %v: %v", request.CustomerID, violations[0])
+			return errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, violations[0].Error())
+		}
+
+		order = prepared
+		order.Status = entities.Pending
+
+		// The customer and products already exist, only the order and its lines are new
+		if err := db.Debug().Model(&entities.Order{}).Omit("Customer", "Items.Product").Create(&order).Error; err != nil {
+			logger.Log.Error("Could not create order: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create order.")
+		}
+
+		if err := enqueueWebhookEvent(db, entities.EventOrderCreated, order); err != nil {
+			logger.Log.Error("Could not queue order webhooks: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create order.")
+		}
+
+		if err := appendOutboxEvent(db, entities.AggregateOrder, order.ID, entities.EventOrderCreated, order); err != nil {
+			logger.Log.Error("Could not record order event: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create order.")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
 	}
 
-	var lastOrder *entities.Order
-	err := db.Debug().Model(&entities.Order{}).Where("customer_id = ?", customerID).Last(&lastOrder).Error
-	if err == nil {
-		if lastOrder.Status == entities.Unfulfilled {
-			logger.Log.Warn("Customer has an unfulfilled order")
-			return nil, errorPkg.CustomErrorHandle(http.StatusBadRequest, fmt.Sprintf("Customer with id '%v' has an unfulfilled order", customerID))
+	logger.Log.Infof("Order created successfully with ID: %v", order.ID)
+	return order, nil
+}
+
+// QuoteOrder runs every check and price calculation of CreateOrder without
+// locking or reserving anything, in a transaction that is always rolled back,
+// returning what the order would cost and the order policy rules it would
+// break
+func (c customerRepository) QuoteOrder(ctx context.Context, request entities.OrderRequest) (*entities.OrderQuote, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
 
+	var order *entities.Order
+	var violations []*policy.Violation
+
+	// Nothing a quote does is ever committed
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		var errs errorPkg.CustomErrors
+		order, violations, errs = c.prepareOrder(db, request, true)
+		if errs != nil {
+			return errs
 		}
+		return errDiscard
+	})
+	if !errors.Is(err, errDiscard) {
+		return nil, txError(err)
 	}
 
-	var products []entities.Product
-	if err := db.Debug().Model(&entities.Product{}).Find(&products, productIds).Error; err != nil {
-		logger.Log.Error("Error retrieving products: ", err)
-		return nil, errorPkg.HandleError(db, err)
+	if violations == nil {
+		violations = []*policy.Violation{}
 	}
 
-	totalPrice := 0.0
+	logger.Log.Infof("Quoted order for customer %v: %v, %d policy violations", request.CustomerID, order.TotalPrice, len(violations))
+	return &entities.OrderQuote{
+		CustomerID:       order.CustomerID,
+		Items:            order.Items,
+		Subtotal:         order.Subtotal,
+		Discounts:        order.Discounts,
+		DiscountTotal:    order.DiscountTotal,
+		TaxLines:         order.TaxLines,
+		TaxTotal:         order.TaxTotal,
+		PricesIncludeTax: order.PricesIncludeTax,
+		ShippingAddress:  order.ShippingAddress,
+		ShippingZone:     order.ShippingZone,
+		ShippingWeight:   order.ShippingWeightGrams,
+		ShippingCost:     order.ShippingCost,
+		TotalPrice:       order.TotalPrice,
+		Violations:       violations,
+	}, nil
+}
 
-	for _, product := range products {
-		totalPrice += product.Price
+// prepareOrder locks the customer, the ordered products and the coupon within
+// db, reserves stock, copies the shipping address and prices the order. Order policy violations are
+// returned rather than treated as errors so quotes can report all of them.
+// In readOnly mode nothing is locked and stock is only checked, not reserved,
+// so quotes never wait on or hold up orders being placed.
+func (c customerRepository) prepareOrder(db *gorm.DB, request entities.OrderRequest, readOnly bool) (*entities.Order, []*policy.Violation, errorPkg.CustomErrors) {
+	customerID, items := request.CustomerID, request.Items
+
+	// Lock the customer so concurrent orders are checked against the policy one at a time
+	var customer entities.Customer
+	if err := forUpdate(db.Debug(), readOnly).Where("id = ?", customerID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Customer with id %v not found.", customerID)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Customer not found.")
+		}
 
+		logger.Log.Error("Error fetching customer: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
 	}
 
-	logger.Log.Infof("Calculated total price for order: %.2f", totalPrice)
+	// The order ships to one of the customer's own addresses
+	var address entities.Address
+	if err := db.Debug().Where("customer_id = ?", customer.ID).First(&address, "id = ?", request.ShippingAddressID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Shipping address %v of customer %v not found.", request.ShippingAddressID, customerID)
+			return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, "Shipping address not found.")
+		}
 
-	totalPrice = math.Round(totalPrice*100) / 100
+		logger.Log.Error("Error fetching shipping address: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
 
-	custId, err := uuid.Parse(customerID)
+	orderPolicy, err := c.customerOrderPolicy(db, customer)
 	if err != nil {
-		logger.Log.Error("Error parsing customerId: ", err)
-		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		logger.Log.Error("Error loading order policy: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
 	}
 
-	order := &entities.Order{
-		Customer:   customer,
-		CustomerID: custId,
-		Products:   products,
-		TotalPrice: totalPrice,
-		Status:     entities.Unfulfilled,
+	var openOrders int64
+	err = db.Debug().Model(&entities.Order{}).Where("customer_id = ? AND status IN ?", customerID, entities.OpenOrderStatuses()).Count(&openOrders).Error
+	if err != nil {
+		logger.Log.Error("Error counting open orders: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
 	}
 
-	if err := db.Debug().Model(&entities.Order{}).Create(&order).Error; err != nil {
-		logger.Log.Error("Could not create order: ", err)
-		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create order.")
+	var violations []*policy.Violation
+	if violation := orderPolicy.CheckOpenOrders(openOrders); violation != nil {
+		violations = append(violations, violation)
 	}
 
-	err = db.Commit().Error
+	productIds := make([]string, 0, len(items))
+	for _, item := range items {
+		productIds = append(productIds, item.ProductID)
+	}
+
+	products, err := lockProducts(db, productIds, readOnly)
 	if err != nil {
-		logger.Log.Error("Error commiting order transaction: ", err)
-		db.Rollback()
-		return nil, errorPkg.HandleError(db, err)
+		logger.Log.Error("Error retrieving products: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
 	}
 
-	logger.Log.Infof("Order created successfully with ID: %v", order.ID)
-	return order, nil
+	productsById := make(map[string]entities.Product, len(products))
+	for _, product := range products {
+		productsById[product.ID.String()] = product
+	}
+
+	// Every requested product must exist in the catalog
+	var missing []string
+	for _, id := range productIds {
+		if _, ok := productsById[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		logger.Log.Warnf("Unknown products in order: %v", missing)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("Unknown products: %v", strings.Join(missing, ", ")))
+	}
+
+	var unavailable []string
+	if readOnly {
+		unavailable = unavailableStock(productsById, items)
+	} else {
+		unavailable, err = reserveStock(db, productsById, items)
+	}
+	if err != nil {
+		logger.Log.Error("Error reserving stock: ", err)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not reserve stock.")
+	}
+
+	if len(unavailable) > 0 {
+		logger.Log.Warnf("Insufficient stock for products: %v", unavailable)
+		return nil, nil, errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Insufficient stock for products: %v", strings.Join(unavailable, ", ")))
+	}
+
+	order, errs := c.priceOrder(db, customer, address, items, productsById, request.CouponCode, readOnly)
+	if errs != nil {
+		return nil, nil, errs
+	}
+
+	logger.Log.Infof("Calculated total price for order: %v (subtotal %v, discount %v, tax %v, shipping %v)", order.TotalPrice, order.Subtotal, order.DiscountTotal, order.TaxTotal, order.ShippingCost)
+
+	if violation := orderPolicy.CheckOrderValue(order.TotalPrice); violation != nil {
+		violations = append(violations, violation)
+	}
+
+	order.Customer = customer
+	order.CustomerID = customer.ID
+
+	return order, violations, nil
 }
 
 // GetOrderByID
-func (c customerRepository) GetOrderByID(orderId string) (*entities.Order, errorPkg.CustomErrors) {
+func (c customerRepository) GetOrderByID(ctx context.Context, orderId string) (*entities.Order, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	// The order and its lines are read as one unit
+	var order *entities.Order
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		if err := db.Debug().Preload("Items.Product", unscopedProducts).Preload("Discounts").Preload("TaxLines").Where("id=?", orderId).First(&order).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				logger.Log.Error("Order not found: ", err)
+				return errorPkg.CustomErrorHandle(http.StatusNotFound, "Order not found.")
+			}
+
+			logger.Log.Error("Error fetching order: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	logger.Log.Infof("Order fetched successfully with ID: %v", order.ID)
+	return order, nil
+}
+
+// orderSorts lists the columns orders can be sorted on
+var orderSorts = timestampSorts(map[string]sortOption[entities.Order]{
+	"total": {Expr: "total_price_amount", Value: func(o entities.Order) string { return strconv.FormatInt(o.TotalPrice.Amount, 10) }},
+}, func(o entities.Order) entities.BaseModel { return o.BaseModel })
+
+// GetOrders returns one page of the orders matching the query
+func (c customerRepository) GetOrders(ctx context.Context, query entities.OrderListQuery) (*entities.Page[entities.Order], errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	db := filterTimestamps(c.db.GetDb().WithContext(ctx).Debug().Model(&entities.Order{}), query.TimestampFilter)
+
+	if query.Status != "" {
+		db = db.Where("status IN ?", strings.Split(query.Status, ","))
+	}
+	if query.CustomerID != "" {
+		db = db.Where("customer_id = ?", query.CustomerID)
+	}
+	if query.Currency != "" {
+		db = db.Where("total_price_currency = ?", query.Currency)
+	}
+	if query.MinTotal > 0 {
+		db = db.Where("total_price_amount >= ?", query.MinTotal)
+	}
+	if query.MaxTotal > 0 {
+		db = db.Where("total_price_amount <= ?", query.MaxTotal)
+	}
+
+	var scopes []func(*gorm.DB) *gorm.DB
+	if query.Include == "items" {
+		scopes = append(scopes, func(db *gorm.DB) *gorm.DB {
+			return db.Preload("Items.Product", unscopedProducts).Preload("Discounts").Preload("TaxLines")
+		})
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "-created_at"
+	}
+
+	page, err := keysetPage(db, pageRequest{Limit: query.Limit, Cursor: query.Cursor, Sort: sort}, orderSorts,
+		func(o entities.Order) string { return o.ID.String() }, scopes...)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			logger.Log.Warn("Invalid cursor for listing orders")
+			return nil, errorPkg.CustomErrorHandle(http.StatusBadRequest, err.Error())
+		}
+
+		logger.Log.Error("Error fetching orders: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Log.Infof("Orders fetched successfully!. Returning %d of %d orders", len(page.Items), page.TotalCount)
+	return page, nil
+}
+
+// UpdateOrderStatus moves the order to the given status if the transition is
+// allowed. Orders only become paid by capturing a payment.
+func (c customerRepository) UpdateOrderStatus(ctx context.Context, orderId string, status entities.OrderStatus) (*entities.Order, errorPkg.CustomErrors) {
+	if status == entities.Paid {
+		logger.Log.Warnf("Order %v cannot be marked paid without a payment", orderId)
+		return nil, errorPkg.CustomErrorHandle(http.StatusConflict, "Orders are marked paid by capturing a payment.")
+	}
+
+	return c.transitionOrder(ctx, orderId, status, nil)
+}
+
+// CancelOrder cancels an order that has not shipped yet, recording the reason
+// and who cancelled it, and gives its reserved stock back. The authorization
+// of its payment is voided and whatever was captured refunded.
+func (c customerRepository) CancelOrder(ctx context.Context, orderId string, request entities.CancelOrderRequest) (*entities.Order, errorPkg.CustomErrors) {
+	fields := map[string]interface{}{
+		"cancel_reason": request.Reason,
+		"cancelled_by":  request.CancelledBy,
+		"cancelled_at":  time.Now(),
+	}
+	if request.Note != "" {
+		fields["cancel_note"] = request.Note
+	}
+
+	return c.transitionOrder(ctx, orderId, entities.Cancelled, fields)
+}
+
+// transitionOrder moves a locked order to status, updating the given extra
+// columns alongside it. Orders whose payment is waiting for its provider keep
+// their status; a cancelled order's payment is voided or refunded through the
+// provider first and the order cancelled where its outcome is recorded.
+func (c customerRepository) transitionOrder(ctx context.Context, orderId string, status entities.OrderStatus, fields map[string]interface{}) (*entities.Order, errorPkg.CustomErrors) {
 	if c.db == nil {
 		logger.Log.Warnf("Database connection not available.")
 		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
 	}
 
-	db := c.db.GetDb().Begin()
+	var (
+		active   *entities.Payment
+		provider payment.Provider
+		change   paymentChange
+		settled  entities.PaymentStatus
+	)
+	err := c.db.WithTx(ctx, func(db *gorm.DB) error {
+		locked, errs := lockOrder(db, orderId)
+		if errs != nil {
+			return errs
+		}
+
+		if active, errs = lockActivePayment(db, orderId); errs != nil {
+			return errs
+		}
+
+		if active != nil && active.Status.IsInFlight() {
+			logger.Log.Warnf("Payment %v of order %v is %v", active.ID, orderId, active.Status)
+			return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Order has a payment that is '%v'", active.Status))
+		}
+
+		if active == nil || status != entities.Cancelled {
+			active = nil
+			if errs := applyOrderTransition(db, locked, status, fields); errs != nil {
+				return errs
+			}
+			return nil
+		}
+
+		if !locked.Status.CanTransitionTo(status) {
+			logger.Log.Warnf("Illegal status transition for order %v: %v -> %v", locked.ID, locked.Status, status)
+			return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Order cannot move from '%v' to '%v'", locked.Status, status))
+		}
+
+		var err error
+		if provider, err = c.payments.Get(active.Provider); err != nil {
+			logger.Log.Error("Payment provider not available: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Payment provider not available.")
+		}
+
+		change = cancelChange(active, fields)
+		if settled, errs = markInFlight(db, active, change); errs != nil {
+			return errs
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	if active != nil {
+		if errs := runPaymentChange(ctx, c.db, provider, *active, settled, change); errs != nil {
+			logger.Log.Warnf("Order %v not cancelled: %v", orderId, errs.Error())
+			return nil, errs
+		}
+	}
 
 	var order *entities.Order
-	if err := db.Debug().Preload("Products").Where("id=?", orderId).First(&order).Error; err != nil {
+	if err := c.db.GetDb().WithContext(ctx).Debug().Preload("Items.Product", unscopedProducts).Preload("Discounts").Preload("TaxLines").First(&order, "id = ?", orderId).Error; err != nil {
+		logger.Log.Error("Error reloading order: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Log.Infof("Order %v moved to status %v", order.ID, order.Status)
+	return order, nil
+}
+
+// lockActivePayment loads the payment holding or having taken the money for
+// the order for update within db, nil when there is none
+func lockActivePayment(db *gorm.DB, orderId string) (*entities.Payment, errorPkg.CustomErrors) {
+	var active []entities.Payment
+	if err := db.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ? AND status IN ?", orderId, entities.ActivePaymentStatuses()).Limit(1).Find(&active).Error; err != nil {
+		logger.Log.Error("Error fetching payments: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	if len(active) == 0 {
+		return nil, nil
+	}
+	return &active[0], nil
+}
+
+// lockOrder loads the order for update within db
+func lockOrder(db *gorm.DB, orderId string) (*entities.Order, errorPkg.CustomErrors) {
+	var order *entities.Order
+	if err := db.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id=?", orderId).First(&order).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			logger.Log.Error("Order not found: ", err)
 			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Order not found.")
 		}
 
 		logger.Log.Error("Error fetching order: ", err)
-		return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, err.Error())
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
 	}
 
-	logger.Log.Infof("Order fetched successfully with ID: %v", order.ID)
 	return order, nil
 }
+
+// applyOrderTransition moves an order locked within db to status if the
+// transition is allowed, releasing its stock when it is cancelled
+func applyOrderTransition(db *gorm.DB, order *entities.Order, status entities.OrderStatus, fields map[string]interface{}) errorPkg.CustomErrors {
+	if !order.Status.CanTransitionTo(status) {
+		logger.Log.Warnf("Illegal status transition for order %v: %v -> %v", order.ID, order.Status, status)
+		return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Order cannot move from '%v' to '%v'", order.Status, status))
+	}
+
+	updates := map[string]interface{}{"status": status}
+	for column, value := range fields {
+		updates[column] = value
+	}
+
+	change := entities.OrderStatusChange{OrderID: order.ID, CustomerID: order.CustomerID, PreviousStatus: order.Status, Status: status}
+
+	if err := db.Debug().Model(order).Updates(updates).Error; err != nil {
+		logger.Log.Error("Could not update order status: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update order status.")
+	}
+
+	if err := enqueueWebhookEvent(db, entities.EventOrderStatusChanged, change); err != nil {
+		logger.Log.Error("Could not queue order webhooks: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update order status.")
+	}
+
+	if err := appendOutboxEvent(db, entities.AggregateOrder, order.ID, entities.EventOrderStatusChanged, change); err != nil {
+		logger.Log.Error("Could not record order event: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update order status.")
+	}
+
+	// A cancelled order gives its reserved stock back
+	if status == entities.Cancelled {
+		if err := releaseStock(db, order.ID.String()); err != nil {
+			logger.Log.Error("Could not release reserved stock: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not release reserved stock.")
+		}
+	}
+
+	return nil
+}
+
+// unscopedProducts preloads products including soft deleted ones so that
+// historical order items keep resolving the product they were placed for
+func unscopedProducts(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
+}
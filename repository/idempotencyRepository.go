@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type idempotencyRepository struct {
+	db database.Database
+}
+
+func NewIdempotencyRepository(db database.Database) IdempotencyHandler {
+	return &idempotencyRepository{db: db}
+}
+
+// ReserveIdempotencyKey claims the key for a new request for the length of
+// lease, after which a request that never completed, e.g. because its
+// instance crashed, no longer holds up retries. When the key is already taken
+// the existing record is returned and created is false.
+func (i idempotencyRepository) ReserveIdempotencyKey(ctx context.Context, endpoint, key, fingerprint string, lease time.Duration) (*entities.IdempotencyKey, bool, errorPkg.CustomErrors) {
+	if i.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, false, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	db := i.db.GetDb().WithContext(ctx).Debug()
+	now := time.Now()
+
+	record := entities.IdempotencyKey{
+		Endpoint:    endpoint,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(lease),
+	}
+
+	// An expired key is free to be used again, so it is taken over in place
+	result := db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "endpoint"}, {Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"fingerprint":     record.Fingerprint,
+			"response_status": nil,
+			"response_body":   nil,
+			"created_at":      record.CreatedAt,
+			"expires_at":      record.ExpiresAt,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Lte{Column: clause.Column{Table: "idempotency_keys", Name: "expires_at"}, Value: now},
+		}},
+	}).Create(&record)
+	if result.Error != nil {
+		logger.Log.Error("Error reserving idempotency key: ", result.Error)
+		return nil, false, errorPkg.CustomErrorHandle(http.StatusInternalServerError, result.Error.Error())
+	}
+	if result.RowsAffected == 1 {
+		return &record, true, nil
+	}
+
+	var existing entities.IdempotencyKey
+	err := db.Where("endpoint = ? AND key = ?", endpoint, key).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Released by a failing request between our insert and this read
+		return nil, false, errorPkg.CustomErrorHandle(http.StatusConflict, "request with this Idempotency-Key is being processed, retry later")
+	}
+	if err != nil {
+		logger.Log.Error("Error fetching idempotency key: ", err)
+		return nil, false, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return &existing, false, nil
+}
+
+// CompleteIdempotencyKey stores the response to replay for retries of the key
+// and keeps it for ttl
+func (i idempotencyRepository) CompleteIdempotencyKey(ctx context.Context, endpoint, key string, status int, body []byte, ttl time.Duration) errorPkg.CustomErrors {
+	if i.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	err := i.db.GetDb().WithContext(ctx).Debug().Model(&entities.IdempotencyKey{}).
+		Where("endpoint = ? AND key = ?", endpoint, key).
+		Updates(map[string]interface{}{"response_status": status, "response_body": body, "expires_at": time.Now().Add(ttl)}).Error
+	if err != nil {
+		logger.Log.Error("Error storing idempotent response: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a key whose request did not complete, so the
+// client can retry it
+func (i idempotencyRepository) ReleaseIdempotencyKey(ctx context.Context, endpoint, key string) errorPkg.CustomErrors {
+	if i.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	err := i.db.GetDb().WithContext(ctx).Debug().
+		Where("endpoint = ? AND key = ? AND response_status IS NULL", endpoint, key).
+		Delete(&entities.IdempotencyKey{}).Error
+	if err != nil {
+		logger.Log.Error("Error releasing idempotency key: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the keys that expired before now
+func (i idempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, errorPkg.CustomErrors) {
+	if i.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return 0, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	result := i.db.GetDb().WithContext(ctx).Debug().Where("expires_at <= ?", now).Delete(&entities.IdempotencyKey{})
+	if result.Error != nil {
+		logger.Log.Error("Error removing expired idempotency keys: ", result.Error)
+		return 0, errorPkg.CustomErrorHandle(http.StatusInternalServerError, result.Error.Error())
+	}
+
+	return result.RowsAffected, nil
+}
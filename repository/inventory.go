@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"gorm.io/gorm"
+)
+
+// lockProducts loads the requested products with a row level lock so their
+// stock cannot change until the surrounding transaction ends. Rows are locked
+// in id order so concurrent orders cannot deadlock each other. A read only
+// unit of work loads them without the lock.
+func lockProducts(db *gorm.DB, productIds []string, readOnly bool) ([]entities.Product, error) {
+	var products []entities.Product
+	err := forUpdate(db.Debug().Model(&entities.Product{}), readOnly).
+		Order("id").
+		Find(&products, productIds).Error
+
+	return products, err
+}
+
+// requestedQuantities adds up the quantities ordered of every product
+func requestedQuantities(items []entities.OrderItemRequest) map[string]int {
+	requested := make(map[string]int, len(items))
+	for _, item := range items {
+		requested[item.ProductID] += item.Quantity
+	}
+	return requested
+}
+
+// unavailableStock returns the ids of the products whose stock cannot cover
+// the ordered quantities
+func unavailableStock(products map[string]entities.Product, items []entities.OrderItemRequest) []string {
+	var unavailable []string
+	for id, quantity := range requestedQuantities(items) {
+		product, ok := products[id]
+		if !ok || product.Stock == nil {
+			continue
+		}
+
+		if *product.Stock < quantity {
+			unavailable = append(unavailable, id)
+		}
+	}
+	return unavailable
+}
+
+// reserveStock takes the ordered quantities out of the stock of the locked
+// products. When any product cannot cover its quantity nothing is reserved
+// and the ids of the unavailable products are returned.
+func reserveStock(db *gorm.DB, products map[string]entities.Product, items []entities.OrderItemRequest) ([]string, error) {
+	if unavailable := unavailableStock(products, items); len(unavailable) > 0 {
+		return unavailable, nil
+	}
+
+	requested := requestedQuantities(items)
+
+	for id, quantity := range requested {
+		product, ok := products[id]
+		if !ok || product.Stock == nil {
+			continue
+		}
+
+		err := db.Debug().Model(&entities.Product{}).Where("id = ?", id).Update("stock", gorm.Expr("stock - ?", quantity)).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// releaseStock returns the quantities reserved by the order to the stock of
+// its products, including products deleted since the order was placed
+func releaseStock(db *gorm.DB, orderId string) error {
+	var items []entities.OrderItem
+	if err := db.Debug().Where("order_id = ?", orderId).Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		err := db.Debug().Unscoped().Model(&entities.Product{}).
+			Where("id = ? AND stock IS NOT NULL", item.ProductID).
+			Update("stock", gorm.Expr("stock + ?", item.Quantity)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
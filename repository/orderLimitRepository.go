@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// customerOrderPolicy returns the order policy of the customer, taking the
+// customer's override into account
+func (c customerRepository) customerOrderPolicy(db *gorm.DB, customer entities.Customer) (policy.Policy, error) {
+	var override *policy.Limits
+
+	var limit entities.CustomerOrderLimit
+	err := db.Debug().Where("customer_id = ?", customer.ID).First(&limit).Error
+	switch {
+	case err == nil:
+		override = &policy.Limits{MaxOpenOrders: limit.MaxOpenOrders}
+		if limit.MaxOrderValue != nil {
+			override.MaxOrderValue = map[string]int64{limit.MaxOrderValue.Currency: limit.MaxOrderValue.Amount}
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return policy.Policy{}, err
+	}
+
+	return c.orderPolicy.For(customer.Country, override), nil
+}
+
+// GetCustomerOrderLimit returns the customer's override of the order policy
+func (c customerRepository) GetCustomerOrderLimit(ctx context.Context, customerID string) (*entities.CustomerOrderLimit, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var limit entities.CustomerOrderLimit
+	if err := c.db.GetDb().WithContext(ctx).Debug().Where("customer_id = ?", customerID).First(&limit).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("No order limits for customer %v", customerID)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Order limits not found.")
+		}
+
+		logger.Log.Error("Error fetching order limits: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return &limit, nil
+}
+
+// SetCustomerOrderLimit creates or replaces the customer's override of the order policy
+func (c customerRepository) SetCustomerOrderLimit(ctx context.Context, customerID string, limit *entities.CustomerOrderLimit) (*entities.CustomerOrderLimit, errorPkg.CustomErrors) {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	if _, errs := c.GetCustomerByID(ctx, customerID); errs != nil {
+		return nil, errs
+	}
+
+	limit.CustomerID = uuid.MustParse(customerID)
+
+	err := c.db.GetDb().WithContext(ctx).Debug().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "customer_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"max_open_orders", "max_order_value_amount", "max_order_value_currency", "updated_at"}),
+	}).Create(limit).Error
+	if err != nil {
+		logger.Log.Error("Could not save order limits: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not save order limits.")
+	}
+
+	logger.Log.Infof("Order limits of customer %v updated", customerID)
+	return c.GetCustomerOrderLimit(ctx, customerID)
+}
+
+// DeleteCustomerOrderLimit removes the customer's override so the configured policy applies again
+func (c customerRepository) DeleteCustomerOrderLimit(ctx context.Context, customerID string) errorPkg.CustomErrors {
+	if c.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	result := c.db.GetDb().WithContext(ctx).Debug().Where("customer_id = ?", customerID).Delete(&entities.CustomerOrderLimit{})
+	if result.Error != nil {
+		logger.Log.Error("Could not delete order limits: ", result.Error)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete order limits.")
+	}
+
+	if result.RowsAffected == 0 {
+		logger.Log.Warnf("No order limits for customer %v", customerID)
+		return errorPkg.CustomErrorHandle(http.StatusNotFound, "Order limits not found.")
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/outbox"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// outboxLockKey identifies the advisory lock held while claiming outbox
+// events, so relays of several instances never claim events out of order
+const outboxLockKey int64 = 7_314_729_348
+
+type outboxRepository struct {
+	db database.Database
+}
+
+func NewOutboxRepository(db database.Database) outbox.Store {
+	return &outboxRepository{db: db}
+}
+
+// ClaimMessages leases up to limit due events to the caller, oldest first.
+// Events behind an unpublished event of their aggregate that is leased or
+// waiting for a retry are left alone to keep every aggregate in order.
+func (or outboxRepository) ClaimMessages(now time.Time, limit int, lease time.Duration) ([]outbox.Message, error) {
+	var claimed []outbox.Message
+
+	err := or.db.WithTx(context.Background(), func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", outboxLockKey).Error; err != nil {
+			return err
+		}
+
+		var events []entities.OutboxEvent
+		err := tx.Debug().
+			Where("published_at IS NULL AND next_attempt_at <= ?", now).
+			Where(`NOT EXISTS (
+				SELECT 1 FROM outbox_events earlier
+				WHERE earlier.aggregate_type = outbox_events.aggregate_type
+				AND earlier.aggregate_id = outbox_events.aggregate_id
+				AND earlier.published_at IS NULL
+				AND earlier.sequence < outbox_events.sequence
+				AND earlier.next_attempt_at > ?)`, now).
+			Order("sequence").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+			claimed = append(claimed, outbox.Message{
+				ID:            event.ID.String(),
+				Sequence:      event.Sequence,
+				AggregateType: event.AggregateType,
+				AggregateID:   event.AggregateID.String(),
+				EventType:     event.EventType,
+				Payload:       event.Payload,
+				CreatedAt:     event.CreatedAt,
+				Attempts:      event.Attempts,
+			})
+		}
+
+		return tx.Debug().Model(&entities.OutboxEvent{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error
+	})
+
+	return claimed, err
+}
+
+// MarkPublished records that the event was published
+func (or outboxRepository) MarkPublished(message outbox.Message, at time.Time) error {
+	return or.db.GetDb().Debug().Model(&entities.OutboxEvent{}).Where("id = ?", message.ID).
+		Updates(map[string]interface{}{
+			"published_at": at,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   "",
+		}).Error
+}
+
+// MarkFailed records a failed attempt to publish the event and when to retry
+func (or outboxRepository) MarkFailed(message outbox.Message, reason string, retryAt time.Time) error {
+	return or.db.GetDb().Debug().Model(&entities.OutboxEvent{}).Where("id = ?", message.ID).
+		Updates(map[string]interface{}{
+			"next_attempt_at": retryAt,
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      reason,
+		}).Error
+}
+
+// appendOutboxEvent records the event within db, so it is only published if
+// the change it describes is committed
+func appendOutboxEvent(db *gorm.DB, aggregateType string, aggregateID uuid.UUID, eventType string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return db.Debug().Omit("Sequence").Create(&entities.OutboxEvent{
+		ID:            uuid.New(),
+		CreatedAt:     now,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       payload,
+		NextAttemptAt: now,
+	}).Error
+}
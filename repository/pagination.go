@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageRequest holds the pagination parameters of a listing. Sort names a key
+// of the listing's sort options, prefixed with '-' for descending order.
+type pageRequest struct {
+	Limit  int
+	Cursor string
+	Sort   string
+}
+
+// sortOption is a column a listing can be sorted on. Expr is the SQL
+// expression ordered by and value reads the same value from a row so the
+// next page can continue after it.
+type sortOption[T any] struct {
+	Expr  string
+	Value func(T) string
+}
+
+// timestampSorts adds the created_at and updated_at sorts every listing
+// supports to its own sort options
+func timestampSorts[T any](sorts map[string]sortOption[T], base func(T) entities.BaseModel) map[string]sortOption[T] {
+	sorts["created_at"] = sortOption[T]{Expr: "created_at", Value: func(row T) string { return base(row).CreatedAt.Format(time.RFC3339Nano) }}
+	sorts["updated_at"] = sortOption[T]{Expr: "updated_at", Value: func(row T) string { return base(row).UpdatedAt.Format(time.RFC3339Nano) }}
+	return sorts
+}
+
+// filterTimestamps restricts the query to the created and updated ranges
+func filterTimestamps(db *gorm.DB, filter entities.TimestampFilter) *gorm.DB {
+	if filter.CreatedFrom != "" {
+		db = db.Where("created_at >= ?", filter.CreatedFrom)
+	}
+	if filter.CreatedTo != "" {
+		db = db.Where("created_at < ?", filter.CreatedTo)
+	}
+	if filter.UpdatedFrom != "" {
+		db = db.Where("updated_at >= ?", filter.UpdatedFrom)
+	}
+	if filter.UpdatedTo != "" {
+		db = db.Where("updated_at < ?", filter.UpdatedTo)
+	}
+	return db
+}
+
+// cursor marks the last row of a page. It is handed to clients base64 encoded
+// and is only valid for the sort it was issued with.
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// escapeLike escapes the LIKE wildcards in a user supplied prefix
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(encoded string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// keysetPage fetches one page of query using keyset pagination on the
+// requested sort, with the row id as tie breaker so pages are stable. Scopes
+// only apply to fetching the page, not to counting the matching rows.
+func keysetPage[T any](query *gorm.DB, req pageRequest, sorts map[string]sortOption[T], idOf func(T) string, scopes ...func(*gorm.DB) *gorm.DB) (*entities.Page[T], error) {
+	query = query.Session(&gorm.Session{})
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	direction, operator := "ASC", ">"
+	key := req.Sort
+	if strings.HasPrefix(key, "-") {
+		direction, operator = "DESC", "<"
+		key = strings.TrimPrefix(key, "-")
+	}
+
+	sort, ok := sorts[key]
+	if !ok {
+		return nil, fmt.Errorf("unsupported sort '%s'", req.Sort)
+	}
+
+	page := &entities.Page[T]{Items: []T{}}
+	if err := query.Count(&page.TotalCount).Error; err != nil {
+		return nil, err
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeCursor(req.Cursor)
+		if err != nil || after.Sort != req.Sort {
+			return nil, ErrInvalidCursor
+		}
+
+		query = query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sort.Expr, operator), after.Value, after.ID)
+	}
+
+	err := query.Scopes(scopes...).
+		Order(fmt.Sprintf("%s %s, id %s", sort.Expr, direction, direction)).
+		Limit(limit + 1).
+		Find(&page.Items).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(cursor{Sort: req.Sort, Value: sort.Value(last), ID: idOf(last)})
+	}
+
+	return page, nil
+}
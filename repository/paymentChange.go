@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recordAttempts is how often the outcome of a provider call is written
+// before the call is undone at the provider
+const recordAttempts = 3
+
+// paymentChange is one call moving the money of a payment through its
+// provider. No lock is held while the provider is called: the payment is
+// marked inFlight in one unit of work, the provider is called outside of any
+// and what it did is recorded in a second one.
+type paymentChange struct {
+	// action names the change in messages, e.g. "capture"
+	action   string
+	inFlight entities.PaymentStatus
+	call     func(provider payment.Provider, existing *entities.Payment) (payment.Transaction, error)
+	// record writes what the provider did to the order and the payment, both
+	// locked within db
+	record func(db *gorm.DB, order *entities.Order, existing *entities.Payment, transaction payment.Transaction) errorPkg.CustomErrors
+	// moveOrder, if set, changes the order along with the payment, also when
+	// the provider's webhook recorded the payment first
+	moveOrder func(db *gorm.DB, order *entities.Order) errorPkg.CustomErrors
+	// undo reverses a call that cannot be recorded, nil when it cannot be
+	undo func(provider payment.Provider, existing *entities.Payment, transaction payment.Transaction) error
+	// failed turns an error of the provider into the error returned, a 502 by
+	// default
+	failed func(err error) errorPkg.CustomErrors
+}
+
+// authorizeChange reserves the amount of a pending payment on the customer's
+// payment method. An authorization that cannot be recorded is voided.
+func authorizeChange(request entities.PaymentRequest) paymentChange {
+	return paymentChange{
+		action:   "authorize",
+		inFlight: entities.PaymentPending,
+		call: func(provider payment.Provider, existing *entities.Payment) (payment.Transaction, error) {
+			return provider.Authorize(payment.AuthorizeRequest{OrderID: existing.OrderID.String(), Amount: existing.Amount, PaymentMethod: request.PaymentMethod})
+		},
+		record: func(db *gorm.DB, order *entities.Order, existing *entities.Payment, authorization payment.Transaction) errorPkg.CustomErrors {
+			existing.ProviderReference = authorization.Reference
+			existing.Status = entities.PaymentAuthorized
+
+			if err := db.Debug().Model(existing).Select("provider_reference", "status").Updates(existing).Error; err != nil {
+				logger.Log.Errorf("Could not record authorization of payment %v: %v", existing.ID, err)
+				return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not record payment.")
+			}
+			return nil
+		},
+		undo: func(provider payment.Provider, existing *entities.Payment, authorization payment.Transaction) error {
+			_, err := provider.Void(authorization.Reference)
+			return err
+		},
+		failed: func(err error) errorPkg.CustomErrors {
+			if errors.Is(err, payment.ErrDeclined) {
+				return errorPkg.CustomErrorHandle(http.StatusPaymentRequired, err.Error())
+			}
+			return errorPkg.CustomErrorHandle(http.StatusBadGateway, "Payment provider failed to authorize the payment.")
+		},
+	}
+}
+
+// captureChange captures the whole authorized amount and moves the order to
+// paid. A capture that cannot be recorded is refunded.
+func captureChange() paymentChange {
+	return paymentChange{
+		action:   "capture",
+		inFlight: entities.PaymentCapturing,
+		call: func(provider payment.Provider, existing *entities.Payment) (payment.Transaction, error) {
+			return provider.Capture(existing.ProviderReference, existing.Amount)
+		},
+		record: func(db *gorm.DB, order *entities.Order, existing *entities.Payment, capture payment.Transaction) errorPkg.CustomErrors {
+			if errs := recordCapture(db, existing, existing.Amount); errs != nil {
+				return errs
+			}
+			return applyOrderTransition(db, order, entities.Paid, nil)
+		},
+		undo: func(provider payment.Provider, existing *entities.Payment, capture payment.Transaction) error {
+			_, err := provider.Refund(existing.ProviderReference, existing.Amount)
+			return err
+		},
+	}
+}
+
+// voidChange releases an authorization that will not be captured
+func voidChange() paymentChange {
+	return paymentChange{
+		action:   "void",
+		inFlight: entities.PaymentVoiding,
+		call: func(provider payment.Provider, existing *entities.Payment) (payment.Transaction, error) {
+			return provider.Void(existing.ProviderReference)
+		},
+		record: func(db *gorm.DB, order *entities.Order, existing *entities.Payment, void payment.Transaction) errorPkg.CustomErrors {
+			if err := db.Debug().Model(existing).Update("status", entities.PaymentVoided).Error; err != nil {
+				logger.Log.Errorf("Could not record void of payment %v: %v", existing.ID, err)
+				return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update payment.")
+			}
+			return nil
+		},
+	}
+}
+
+// refundChange gives amount of the captured money back
+func refundChange(amount money.Money, reason string) paymentChange {
+	return paymentChange{
+		action:   "refund",
+		inFlight: entities.PaymentRefunding,
+		call: func(provider payment.Provider, existing *entities.Payment) (payment.Transaction, error) {
+			return provider.Refund(existing.ProviderReference, amount)
+		},
+		record: func(db *gorm.DB, order *entities.Order, existing *entities.Payment, refund payment.Transaction) errorPkg.CustomErrors {
+			return recordRefund(db, existing, amount, refund.Reference, reason)
+		},
+	}
+}
+
+// cancelChange gives the money of the order's active payment back, voiding
+// an authorization and refunding what is left of a capture, and cancels the
+// order with fields where the outcome is recorded
+func cancelChange(active *entities.Payment, fields map[string]interface{}) paymentChange {
+	change := voidChange()
+	if active.Status != entities.PaymentAuthorized {
+		remaining, _ := active.Captured.Subtract(active.Refunded)
+		change = refundChange(remaining, "order cancelled")
+	}
+
+	change.moveOrder = func(db *gorm.DB, order *entities.Order) errorPkg.CustomErrors {
+		return applyOrderTransition(db, order, entities.Cancelled, fields)
+	}
+	return change
+}
+
+// markInFlight marks the payment, locked within db, as waiting for its
+// provider and returns the status it had
+func markInFlight(db *gorm.DB, existing *entities.Payment, change paymentChange) (entities.PaymentStatus, errorPkg.CustomErrors) {
+	settled := existing.Status
+	if err := db.Debug().Model(existing).Update("status", change.inFlight).Error; err != nil {
+		logger.Log.Error("Could not update payment: ", err)
+		return "", errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update payment.")
+	}
+
+	existing.Status = change.inFlight
+	return settled, nil
+}
+
+// runPaymentChange calls the provider for a payment marked in flight and
+// records what it did. A payment the provider refused goes back to settled.
+// A call that cannot be recorded is undone and the payment marked failed;
+// one that cannot be undone leaves the payment in flight and is logged to be
+// reconciled, e.g. by the provider's webhook reporting it.
+func runPaymentChange(ctx context.Context, db database.Database, provider payment.Provider, existing entities.Payment, settled entities.PaymentStatus, change paymentChange) errorPkg.CustomErrors {
+	// What the provider did is recorded even when the request is cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	transaction, err := change.call(provider, &existing)
+	if err != nil {
+		logger.Log.Errorf("Payment provider failed to %v payment %v: %v", change.action, existing.ID, err)
+
+		reason := ""
+		if settled == entities.PaymentFailed {
+			reason = err.Error()
+		}
+		settlePayment(ctx, db, existing, change.inFlight, settled, reason)
+
+		if change.failed != nil {
+			return change.failed(err)
+		}
+		return errorPkg.CustomErrorHandle(http.StatusBadGateway, fmt.Sprintf("Payment provider failed to %v the payment.", change.action))
+	}
+
+	var errs errorPkg.CustomErrors
+	for attempt := 1; attempt <= recordAttempts; attempt++ {
+		errs = recordPaymentChange(ctx, db, existing, transaction, change)
+		if errs == nil {
+			return nil
+		}
+		if errs.HttpStatusCode() < http.StatusInternalServerError {
+			break
+		}
+		logger.Log.Warnf("Could not record %v of payment %v (attempt %d of %d): %v", change.action, existing.ID, attempt, recordAttempts, errs.Error())
+	}
+
+	if change.undo == nil {
+		logger.Log.Errorf("The %v of payment %v at %v (%v) is not recorded, the payment is left %v to be reconciled: %v", change.action, existing.ID, provider.Name(), transaction.Reference, change.inFlight, errs.Error())
+		return errs
+	}
+
+	if err := change.undo(provider, &existing, transaction); err != nil {
+		logger.Log.Errorf("The %v of payment %v at %v (%v) is neither recorded nor undone, the payment is left %v to be reconciled: %v", change.action, existing.ID, provider.Name(), transaction.Reference, change.inFlight, err)
+		return errs
+	}
+
+	settlePayment(ctx, db, existing, change.inFlight, entities.PaymentFailed, fmt.Sprintf("%v could not be recorded and was reversed: %v", change.action, errs.Error()))
+	return errs
+}
+
+// recordPaymentChange locks the order and the payment again and records what
+// the provider did, unless the provider's webhook already did
+func recordPaymentChange(ctx context.Context, db database.Database, existing entities.Payment, transaction payment.Transaction, change paymentChange) errorPkg.CustomErrors {
+	err := db.WithTx(ctx, func(tx *gorm.DB) error {
+		order, errs := lockOrder(tx, existing.OrderID.String())
+		if errs != nil {
+			return errs
+		}
+
+		var locked entities.Payment
+		if err := tx.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", existing.ID).Error; err != nil {
+			logger.Log.Error("Error fetching payment: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		if locked.Status != change.inFlight {
+			logger.Log.Infof("The %v of payment %v was already recorded, it is %v", change.action, existing.ID, locked.Status)
+		} else if errs := change.record(tx, order, &locked, transaction); errs != nil {
+			return errs
+		}
+
+		if change.moveOrder != nil {
+			if errs := change.moveOrder(tx, order); errs != nil {
+				return errs
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return txError(err)
+	}
+
+	return nil
+}
+
+// settlePayment moves a payment that is still in flight to status
+func settlePayment(ctx context.Context, db database.Database, existing entities.Payment, inFlight, status entities.PaymentStatus, reason string) {
+	updates := map[string]interface{}{"status": status}
+	if reason != "" {
+		updates["failure_reason"] = reason
+	}
+
+	err := db.GetDb().WithContext(ctx).Debug().Model(&entities.Payment{}).
+		Where("id = ? AND status = ?", existing.ID, inFlight).
+		Updates(updates).Error
+	if err != nil {
+		logger.Log.Errorf("Could not move payment %v from %v to %v, it needs to be reconciled: %v", existing.ID, inFlight, status, err)
+	}
+}
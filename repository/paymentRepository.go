@@ -0,0 +1,305 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type paymentRepository struct {
+	db        database.Database
+	providers *payment.Registry
+	provider  string
+}
+
+// NewPaymentRepository returns a repository taking new payments through the
+// named provider of the registry
+func NewPaymentRepository(db database.Database, providers *payment.Registry, provider string) PaymentHandler {
+	return &paymentRepository{db: db, providers: providers, provider: provider}
+}
+
+// GetOrderPayments returns every payment attempt of the order, oldest first
+func (pr paymentRepository) GetOrderPayments(ctx context.Context, orderId string) ([]entities.Payment, errorPkg.CustomErrors) {
+	if pr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var order entities.Order
+	if err := pr.db.GetDb().WithContext(ctx).Debug().Select("id").Where("id = ?", orderId).First(&order).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Order with id %v not found.", orderId)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Order not found.")
+		}
+
+		logger.Log.Error("Error fetching order: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	payments := []entities.Payment{}
+	if err := pr.db.GetDb().WithContext(ctx).Debug().Preload("Refunds").Where("order_id = ?", orderId).Order("created_at, id").Find(&payments).Error; err != nil {
+		logger.Log.Error("Error fetching payments: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return payments, nil
+}
+
+// CreatePayment authorizes the total of a pending order with the configured
+// provider and, unless the request only authorizes, captures it and marks the
+// order paid. The payment is recorded as pending before the provider is
+// called; declined payments are kept as failed.
+func (pr paymentRepository) CreatePayment(ctx context.Context, orderId string, request entities.PaymentRequest) (*entities.Payment, errorPkg.CustomErrors) {
+	if pr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	provider, err := pr.providers.Get(pr.provider)
+	if err != nil {
+		logger.Log.Error("Payment provider not available: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Payment provider not available.")
+	}
+
+	newPayment := &entities.Payment{Provider: provider.Name(), Status: entities.PaymentPending}
+
+	err = pr.db.WithTx(ctx, func(db *gorm.DB) error {
+		order, errs := lockOrder(db, orderId)
+		if errs != nil {
+			return errs
+		}
+
+		if order.Status != entities.Pending {
+			logger.Log.Warnf("Order %v is %v and cannot be paid", orderId, order.Status)
+			return errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Only pending orders can be paid, order is '%v'", order.Status))
+		}
+
+		var active int64
+		if err := db.Debug().Model(&entities.Payment{}).Where("order_id = ? AND status IN ?", orderId, entities.ActivePaymentStatuses()).Count(&active).Error; err != nil {
+			logger.Log.Error("Error counting payments: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		if active > 0 {
+			logger.Log.Warnf("Order %v already has a payment", orderId)
+			return errorPkg.CustomErrorHandle(http.StatusConflict, "Order already has a payment.")
+		}
+
+		newPayment.OrderID = order.ID
+		newPayment.Amount = order.TotalPrice
+		newPayment.Captured = money.Zero(order.TotalPrice.Currency)
+		newPayment.Refunded = money.Zero(order.TotalPrice.Currency)
+
+		if err := db.Debug().Create(newPayment).Error; err != nil {
+			logger.Log.Error("Could not record payment: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not record payment.")
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	// Declined and failed attempts are kept so the order shows why it is
+	// still unpaid
+	if errs := runPaymentChange(ctx, pr.db, provider, *newPayment, entities.PaymentFailed, authorizeChange(request)); errs != nil {
+		logger.Log.Warnf("Payment %v for order %v not authorized: %v", newPayment.ID, orderId, errs.Error())
+		return nil, errs
+	}
+
+	if !request.AuthorizeOnly {
+		if _, errs := pr.CapturePayment(ctx, orderId, newPayment.ID.String()); errs != nil {
+			// Release the authorization unless the capture went through and
+			// was reversed or is left to be reconciled
+			if _, voidErrs := pr.VoidPayment(ctx, orderId, newPayment.ID.String()); voidErrs != nil {
+				logger.Log.Warnf("Could not void payment %v after its capture failed: %v", newPayment.ID, voidErrs.Error())
+			}
+			return nil, errs
+		}
+	}
+
+	found, errs := pr.getPayment(ctx, orderId, newPayment.ID.String())
+	if errs != nil {
+		return nil, errs
+	}
+
+	logger.Log.Infof("Payment %v for order %v is %v", found.ID, orderId, found.Status)
+	return found, nil
+}
+
+// CapturePayment captures an authorized payment and marks the order paid
+func (pr paymentRepository) CapturePayment(ctx context.Context, orderId, paymentId string) (*entities.Payment, errorPkg.CustomErrors) {
+	return pr.updatePayment(ctx, orderId, paymentId, func(order *entities.Order, existing *entities.Payment) (paymentChange, errorPkg.CustomErrors) {
+		if existing.Status != entities.PaymentAuthorized {
+			return paymentChange{}, errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Only authorized payments can be captured, payment is '%v'", existing.Status))
+		}
+
+		// Check the order can still be paid before any money moves
+		if !order.Status.CanTransitionTo(entities.Paid) {
+			logger.Log.Warnf("Order %v is %v and cannot be paid", order.ID, order.Status)
+			return paymentChange{}, errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Order cannot move from '%v' to '%v'", order.Status, entities.Paid))
+		}
+
+		return captureChange(), nil
+	})
+}
+
+// VoidPayment releases an authorized payment that will not be captured
+func (pr paymentRepository) VoidPayment(ctx context.Context, orderId, paymentId string) (*entities.Payment, errorPkg.CustomErrors) {
+	return pr.updatePayment(ctx, orderId, paymentId, func(order *entities.Order, existing *entities.Payment) (paymentChange, errorPkg.CustomErrors) {
+		if existing.Status != entities.PaymentAuthorized {
+			return paymentChange{}, errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Only authorized payments can be voided, payment is '%v'", existing.Status))
+		}
+
+		return voidChange(), nil
+	})
+}
+
+// RefundPayment gives back part or, by default, all of the captured amount
+// that has not been refunded yet. The order keeps its status.
+func (pr paymentRepository) RefundPayment(ctx context.Context, orderId, paymentId string, request entities.RefundRequest) (*entities.Payment, errorPkg.CustomErrors) {
+	return pr.updatePayment(ctx, orderId, paymentId, func(order *entities.Order, existing *entities.Payment) (paymentChange, errorPkg.CustomErrors) {
+		if existing.Status != entities.PaymentCaptured && existing.Status != entities.PaymentPartiallyRefunded {
+			return paymentChange{}, errorPkg.CustomErrorHandle(http.StatusConflict, fmt.Sprintf("Only captured payments can be refunded, payment is '%v'", existing.Status))
+		}
+
+		remaining, _ := existing.Captured.Subtract(existing.Refunded)
+		amount := remaining
+		if request.Amount != nil {
+			amount = money.New(*request.Amount, remaining.Currency)
+		}
+
+		if amount.Amount > remaining.Amount {
+			return paymentChange{}, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("At most %v can be refunded", remaining))
+		}
+
+		return refundChange(amount, request.Reason), nil
+	})
+}
+
+// updatePayment marks the payment, with its order locked, in flight for the
+// change prepare returns, then runs the change through the provider once the
+// locks are released
+func (pr paymentRepository) updatePayment(ctx context.Context, orderId, paymentId string, prepare func(order *entities.Order, existing *entities.Payment) (paymentChange, errorPkg.CustomErrors)) (*entities.Payment, errorPkg.CustomErrors) {
+	if pr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var (
+		existing entities.Payment
+		provider payment.Provider
+		change   paymentChange
+		settled  entities.PaymentStatus
+	)
+	err := pr.db.WithTx(ctx, func(db *gorm.DB) error {
+		order, errs := lockOrder(db, orderId)
+		if errs != nil {
+			return errs
+		}
+
+		if err := db.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("order_id = ?", orderId).First(&existing, "id = ?", paymentId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Log.Warnf("Payment %v of order %v not found.", paymentId, orderId)
+				return errorPkg.CustomErrorHandle(http.StatusNotFound, "Payment not found.")
+			}
+
+			logger.Log.Error("Error fetching payment: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		var err error
+		if provider, err = pr.providers.Get(existing.Provider); err != nil {
+			logger.Log.Error("Payment provider not available: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Payment provider not available.")
+		}
+
+		if change, errs = prepare(order, &existing); errs != nil {
+			logger.Log.Warn("Error updating payment: ", errs.Error())
+			return errs
+		}
+
+		if settled, errs = markInFlight(db, &existing, change); errs != nil {
+			return errs
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	if errs := runPaymentChange(ctx, pr.db, provider, existing, settled, change); errs != nil {
+		logger.Log.Warn("Error updating payment: ", errs.Error())
+		return nil, errs
+	}
+
+	logger.Log.Infof("Payment %v of order %v: %v done", paymentId, orderId, change.action)
+	return pr.getPayment(ctx, orderId, paymentId)
+}
+
+func (pr paymentRepository) getPayment(ctx context.Context, orderId, paymentId string) (*entities.Payment, errorPkg.CustomErrors) {
+	var found entities.Payment
+	if err := pr.db.GetDb().WithContext(ctx).Debug().Preload("Refunds").Where("order_id = ?", orderId).First(&found, "id = ?", paymentId).Error; err != nil {
+		logger.Log.Error("Error fetching payment: ", err)
+		return nil, errorPkg.HandleError(pr.db.GetDb().WithContext(ctx), err)
+	}
+
+	return &found, nil
+}
+
+// recordCapture marks the payment captured for amount
+func recordCapture(db *gorm.DB, existing *entities.Payment, amount money.Money) errorPkg.CustomErrors {
+	existing.Status = entities.PaymentCaptured
+	existing.Captured = amount
+
+	if err := db.Debug().Model(existing).Select("status", "captured_amount", "captured_currency").Updates(existing).Error; err != nil {
+		// The money is already taken, leave a trace to reconcile it
+		logger.Log.Errorf("Could not record capture of payment %v: %v", existing.ID, err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not record capture.")
+	}
+
+	return nil
+}
+
+// recordRefund adds a refund the provider made to the payment
+func recordRefund(db *gorm.DB, existing *entities.Payment, amount money.Money, reference, reason string) errorPkg.CustomErrors {
+	refunded, err := existing.Refunded.Add(amount)
+	if err != nil || refunded.Amount > existing.Captured.Amount {
+		logger.Log.Errorf("Refund %v of %v exceeds what is left of payment %v", reference, amount, existing.ID)
+		return errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("Refund of %v exceeds the captured amount", amount))
+	}
+
+	existing.Refunded = refunded
+	existing.Status = entities.PaymentPartiallyRefunded
+	if existing.Refunded.Amount == existing.Captured.Amount {
+		existing.Status = entities.PaymentRefunded
+	}
+
+	err = db.Debug().Create(&entities.Refund{
+		PaymentID:         existing.ID,
+		ProviderReference: reference,
+		Amount:            amount,
+		Reason:            reason,
+	}).Error
+	if err == nil {
+		err = db.Debug().Model(existing).Select("status", "refunded_amount", "refunded_currency").Updates(existing).Error
+	}
+	if err != nil {
+		// The money is already on its way back, leave a trace to reconcile it
+		logger.Log.Errorf("Could not record refund %v of payment %v: %v", reference, existing.ID, err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not record refund.")
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReceivePaymentWebhook stores a verified webhook of the provider and applies
+// it to its payment and order in one unit of work. An event the provider sent
+// before is not applied again; it is returned as it was stored, together
+// with true.
+func (pr paymentRepository) ReceivePaymentWebhook(ctx context.Context, provider string, event payment.Event, payload []byte) (*entities.PaymentWebhookEvent, bool, errorPkg.CustomErrors) {
+	if pr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, false, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	record := &entities.PaymentWebhookEvent{
+		Provider: strings.ToLower(provider),
+		EventID:  event.ID,
+		Type:     event.Type,
+		Status:   entities.WebhookReceived,
+		Payload:  payload,
+	}
+
+	err := pr.db.WithTx(ctx, func(db *gorm.DB) error {
+		// Concurrent deliveries of the same event wait here for the first one
+		result := db.Debug().Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			logger.Log.Error("Could not store payment webhook: ", result.Error)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not store webhook.")
+		}
+
+		if result.RowsAffected == 0 {
+			return errDiscard
+		}
+
+		if errs := applyPaymentEvent(db, record, event); errs != nil {
+			return errs
+		}
+		return nil
+	})
+
+	if errors.Is(err, errDiscard) {
+		var existing entities.PaymentWebhookEvent
+		if err := pr.db.GetDb().WithContext(ctx).Debug().Where("provider = ? AND event_id = ?", record.Provider, event.ID).First(&existing).Error; err != nil {
+			logger.Log.Error("Error fetching payment webhook: ", err)
+			return nil, false, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		logger.Log.Infof("Payment webhook %v of %v already received", event.ID, provider)
+		return &existing, true, nil
+	}
+
+	if err != nil {
+		return nil, false, txError(err)
+	}
+
+	logger.Log.Infof("Payment webhook %v of %v %v", event.ID, provider, record.Status)
+	return record, false, nil
+}
+
+// ReplayPaymentWebhook applies a stored webhook that was ignored or failed
+// again, e.g. once the payment it refers to has been recorded
+func (pr paymentRepository) ReplayPaymentWebhook(ctx context.Context, id string) (*entities.PaymentWebhookEvent, errorPkg.CustomErrors) {
+	if pr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var record entities.PaymentWebhookEvent
+	err := pr.db.WithTx(ctx, func(db *gorm.DB) error {
+		if err := db.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Log.Warnf("Payment webhook %v not found.", id)
+				return errorPkg.CustomErrorHandle(http.StatusNotFound, "Webhook event not found.")
+			}
+
+			logger.Log.Error("Error fetching payment webhook: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		if record.Status == entities.WebhookProcessed {
+			logger.Log.Warnf("Payment webhook %v is already processed", id)
+			return errorPkg.CustomErrorHandle(http.StatusConflict, "Webhook event is already processed.")
+		}
+
+		event, err := payment.ParseEvent(record.Payload)
+		if err != nil {
+			logger.Log.Error("Stored payment webhook cannot be parsed: ", err)
+			return errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, err.Error())
+		}
+
+		record.Error = ""
+		if errs := applyPaymentEvent(db, &record, event); errs != nil {
+			return errs
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	logger.Log.Infof("Payment webhook %v replayed: %v", id, record.Status)
+	return &record, nil
+}
+
+// applyPaymentEvent applies the event to its payment and order within db and
+// saves the outcome on record. Events that cannot be applied are recorded as
+// ignored or failed; only database errors are returned.
+func applyPaymentEvent(db *gorm.DB, record *entities.PaymentWebhookEvent, event payment.Event) errorPkg.CustomErrors {
+	status, reason, errs := changePaymentForEvent(db, record, event)
+	if errs != nil {
+		return errs
+	}
+
+	now := time.Now()
+	record.Status = status
+	record.Error = reason
+	record.ProcessedAt = &now
+
+	if err := db.Debug().Model(record).Select("order_id", "payment_id", "status", "error", "processed_at").Updates(record).Error; err != nil {
+		logger.Log.Error("Could not update payment webhook: ", err)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update webhook.")
+	}
+
+	return nil
+}
+
+func changePaymentForEvent(db *gorm.DB, record *entities.PaymentWebhookEvent, event payment.Event) (entities.PaymentWebhookStatus, string, errorPkg.CustomErrors) {
+	var existing entities.Payment
+	err := db.Debug().Where("provider = ? AND provider_reference = ?", record.Provider, event.PaymentReference).First(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entities.WebhookIgnored, fmt.Sprintf("unknown payment %s", event.PaymentReference), nil
+		}
+
+		logger.Log.Error("Error fetching payment: ", err)
+		return "", "", errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	record.PaymentID = &existing.ID
+	record.OrderID = &existing.OrderID
+
+	// Lock the order before the payment, like the payment endpoints do
+	order, errs := lockOrder(db, existing.OrderID.String())
+	if errs != nil {
+		return "", "", errs
+	}
+
+	if err := db.Debug().Clauses(clause.Locking{Strength: "UPDATE"}).First(&existing, "id = ?", existing.ID).Error; err != nil {
+		logger.Log.Error("Error fetching payment: ", err)
+		return "", "", errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	// A payment in flight is waiting for the outcome of the very call the
+	// event reports, which then finds it recorded
+	switch event.Type {
+	case payment.EventCaptured:
+		if existing.Status != entities.PaymentAuthorized && existing.Status != entities.PaymentCapturing {
+			return ignoreOrFail(existing.Status != entities.PaymentVoided && existing.Status != entities.PaymentFailed, existing, "captured")
+		}
+
+		amount := existing.Amount
+		if event.Amount != nil {
+			amount = *event.Amount
+		}
+		if amount.Currency != existing.Amount.Currency || amount.Amount > existing.Amount.Amount {
+			return entities.WebhookFailed, fmt.Sprintf("capture of %v exceeds the authorized %v", amount, existing.Amount), nil
+		}
+
+		if errs := recordCapture(db, &existing, amount); errs != nil {
+			return "", "", errs
+		}
+
+		// The money is taken either way, an order that can no longer be paid
+		// needs someone to refund it
+		if !order.Status.CanTransitionTo(entities.Paid) {
+			return entities.WebhookFailed, fmt.Sprintf("payment captured but order is '%v'", order.Status), nil
+		}
+		if errs := applyOrderTransition(db, order, entities.Paid, nil); errs != nil {
+			return "", "", errs
+		}
+
+	case payment.EventRefunded:
+		var refunds int64
+		if err := db.Debug().Model(&entities.Refund{}).Where("payment_id = ? AND provider_reference = ?", existing.ID, event.Reference).Count(&refunds).Error; err != nil {
+			logger.Log.Error("Error counting refunds: ", err)
+			return "", "", errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+		}
+
+		// Refunds made through our own endpoint are reported back as well
+		if refunds > 0 {
+			return entities.WebhookIgnored, "refund already recorded", nil
+		}
+
+		if existing.Status != entities.PaymentCaptured && existing.Status != entities.PaymentPartiallyRefunded && existing.Status != entities.PaymentRefunding {
+			return entities.WebhookFailed, fmt.Sprintf("payment is '%v' and cannot be refunded", existing.Status), nil
+		}
+
+		if errs := recordRefund(db, &existing, *event.Amount, event.Reference, event.Reason); errs != nil {
+			if errs.HttpStatusCode() >= http.StatusInternalServerError {
+				return "", "", errs
+			}
+			return entities.WebhookFailed, errs.Error(), nil
+		}
+
+	case payment.EventVoided, payment.EventFailed:
+		status := entities.PaymentVoided
+		if event.Type == payment.EventFailed {
+			status = entities.PaymentFailed
+		}
+
+		if existing.Status != entities.PaymentAuthorized && existing.Status != entities.PaymentVoiding {
+			return ignoreOrFail(existing.Status == status, existing, string(status))
+		}
+
+		if err := db.Debug().Model(&existing).Updates(map[string]interface{}{"status": status, "failure_reason": event.Reason}).Error; err != nil {
+			logger.Log.Error("Could not update payment: ", err)
+			return "", "", errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update payment.")
+		}
+	}
+
+	return entities.WebhookProcessed, "", nil
+}
+
+// ignoreOrFail records an event that does not change the payment, ignored
+// when the payment already got there and failed when it cannot get there
+func ignoreOrFail(alreadyApplied bool, existing entities.Payment, change string) (entities.PaymentWebhookStatus, string, errorPkg.CustomErrors) {
+	if alreadyApplied {
+		return entities.WebhookIgnored, fmt.Sprintf("payment is already '%v'", existing.Status), nil
+	}
+	return entities.WebhookFailed, fmt.Sprintf("payment is '%v' and cannot be %s", existing.Status, change), nil
+}
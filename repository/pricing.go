@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/tax"
+	"gorm.io/gorm"
+)
+
+// priceOrder builds the lines of an order from the current product prices and
+// works out its subtotal, coupon discount, tax, shipping cost and total. The
+// coupon, if any, is locked within db until the transaction ends unless the
+// order is only being quoted.
+func (c customerRepository) priceOrder(db *gorm.DB, customer entities.Customer, address entities.Address, items []entities.OrderItemRequest, productsById map[string]entities.Product, couponCode string, readOnly bool) (*entities.Order, errorPkg.CustomErrors) {
+	order := &entities.Order{}
+
+	// Snapshot the current price of every product onto its order line
+	for _, item := range items {
+		product := productsById[item.ProductID]
+
+		lineTotal := product.Price.Multiply(int64(item.Quantity))
+		if len(order.Items) == 0 {
+			order.Subtotal = money.Zero(lineTotal.Currency)
+		}
+
+		subtotal, err := order.Subtotal.Add(lineTotal)
+		if err != nil {
+			logger.Log.Warn("Order mixes currencies: ", err)
+			return nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, fmt.Sprintf("Order cannot mix currencies: %v", err))
+		}
+		order.Subtotal = subtotal
+		order.ShippingWeightGrams += product.WeightGrams * item.Quantity
+
+		order.Items = append(order.Items, entities.OrderItem{
+			ProductID: product.ID,
+			Product:   product,
+			Quantity:  item.Quantity,
+			UnitPrice: product.Price,
+			LineTotal: lineTotal,
+		})
+	}
+
+	currency := order.Subtotal.Currency
+	order.DiscountTotal = money.Zero(currency)
+
+	var coupon *entities.Coupon
+	if couponCode != "" {
+		lines := make([]entities.CouponLine, 0, len(items))
+		for _, item := range items {
+			product := productsById[item.ProductID]
+			lines = append(lines, entities.CouponLine{Category: product.Category, Quantity: item.Quantity, UnitPrice: product.Price})
+		}
+
+		discount, redeemed, errs := redeemCoupon(db, couponCode, customer.ID, lines, readOnly)
+		if errs != nil {
+			return nil, errs
+		}
+
+		coupon = redeemed
+		order.DiscountTotal = discount.Amount
+		order.Discounts = append(order.Discounts, *discount)
+	}
+
+	// Spread the discount over the lines it applies to, so every line is
+	// taxed on what is actually paid for it
+	weights := make([]int64, len(order.Items))
+	for i, item := range order.Items {
+		if coupon == nil || coupon.AppliesTo(productsById[item.ProductID.String()].Category) {
+			weights[i] = item.LineTotal.Amount
+		}
+	}
+	lineDiscounts := order.DiscountTotal.Allocate(weights)
+
+	taxLines := make([]tax.Line, len(order.Items))
+	for i, item := range order.Items {
+		net, _ := item.LineTotal.Subtract(lineDiscounts[i])
+		taxLines[i] = tax.Line{Category: productsById[item.ProductID.String()].Category, Amount: net}
+	}
+
+	order.TaxTotal = money.Zero(currency)
+	order.PricesIncludeTax = c.taxes.PricesIncludeTax()
+	for _, result := range c.taxes.Calculate(address.Country, taxLines) {
+		order.TaxTotal, _ = order.TaxTotal.Add(result.Amount)
+		order.TaxLines = append(order.TaxLines, entities.OrderTaxLine{
+			Name:            result.Rate.Name,
+			RateBasisPoints: result.Rate.BasisPoints,
+			Taxable:         result.Taxable,
+			Amount:          result.Amount,
+		})
+	}
+
+	// Shipping is charged as is, it is neither discounted nor taxed
+	shipping, err := c.shipping.Calculate(address.Country, order.ShippingWeightGrams, currency)
+	if err != nil {
+		logger.Log.Warn("Order cannot be shipped: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	order.ShippingAddressID = &address.ID
+	order.ShippingAddress = address.Snapshot()
+	order.ShippingZone = shipping.Zone
+	order.ShippingCost = shipping.Cost
+
+	// The discount never exceeds the subtotal and shares its currency
+	order.TotalPrice, _ = order.Subtotal.Subtract(order.DiscountTotal)
+	if !order.PricesIncludeTax {
+		order.TotalPrice, _ = order.TotalPrice.Add(order.TaxTotal)
+	}
+	order.TotalPrice, _ = order.TotalPrice.Add(order.ShippingCost)
+
+	return order, nil
+}
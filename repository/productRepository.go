@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"gorm.io/gorm"
+)
+
+type productRepository struct {
+	db database.Database
+}
+
+func NewProductRepository(db database.Database) ProductHandler {
+	return &productRepository{db: db}
+}
+
+// productSorts lists the columns products can be sorted on
+var productSorts = timestampSorts(map[string]sortOption[entities.Product]{
+	"name":  {Expr: "name", Value: func(p entities.Product) string { return p.Name }},
+	"price": {Expr: "price_amount", Value: func(p entities.Product) string { return strconv.FormatInt(p.Price.Amount, 10) }},
+}, func(p entities.Product) entities.BaseModel { return p.BaseModel })
+
+// GetAllProducts returns one page of the catalog matching the query
+func (p productRepository) GetAllProducts(ctx context.Context, query entities.ProductListQuery) (*entities.Page[entities.Product], errorPkg.CustomErrors) {
+	if p.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	db := filterTimestamps(p.db.GetDb().WithContext(ctx).Debug().Model(&entities.Product{}), query.TimestampFilter)
+
+	if query.Category != "" {
+		db = db.Where("category = ?", query.Category)
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "name"
+	}
+
+	page, err := keysetPage(db, pageRequest{Limit: query.Limit, Cursor: query.Cursor, Sort: sort}, productSorts,
+		func(p entities.Product) string { return p.ID.String() })
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			logger.Log.Warn("Invalid cursor for listing products")
+			return nil, errorPkg.CustomErrorHandle(http.StatusBadRequest, err.Error())
+		}
+
+		logger.Log.Error("Error fetching products: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Log.Infof("Products fetched successfully!. Returning %d of %d products", len(page.Items), page.TotalCount)
+	return page, nil
+}
+
+// GetProductByID retrives the product from database by provided Id
+func (p productRepository) GetProductByID(ctx context.Context, id string) (*entities.Product, errorPkg.CustomErrors) {
+	if p.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var product *entities.Product
+	if err := p.db.GetDb().WithContext(ctx).Debug().Where("id=?", id).First(&product).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			logger.Log.Warnf("Product with id %v not found.", id)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Product not found.")
+		}
+
+		logger.Log.Error("Error fetching product: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	logger.Log.Infof("Product fetched successfully with ID: %v", id)
+	return product, nil
+}
+
+// CreateProduct adds a new product to the catalog
+func (p productRepository) CreateProduct(ctx context.Context, product *entities.Product) (*entities.Product, errorPkg.CustomErrors) {
+	if p.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	if err := p.db.GetDb().WithContext(ctx).Debug().Create(product).Error; err != nil {
+		logger.Log.Error("Could not create product: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create product.")
+	}
+
+	logger.Log.Infof("Product created successfully with ID: %v", product.ID)
+	return product, nil
+}
+
+// UpdateProduct replaces the name, category, price, stock and weight of an existing product
+func (p productRepository) UpdateProduct(ctx context.Context, id string, product *entities.Product) (*entities.Product, errorPkg.CustomErrors) {
+	existing, errs := p.GetProductByID(ctx, id)
+	if errs != nil {
+		return nil, errs
+	}
+
+	existing.Name = product.Name
+	existing.Category = product.Category
+	existing.Price = product.Price
+	existing.Stock = product.Stock
+	existing.WeightGrams = product.WeightGrams
+
+	err := p.db.GetDb().WithContext(ctx).Debug().Model(existing).Select("name", "category", "price_amount", "price_currency", "stock", "weight_grams").Updates(existing).Error
+	if err != nil {
+		logger.Log.Error("Could not update product: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update product.")
+	}
+
+	logger.Log.Infof("Product updated successfully with ID: %v", existing.ID)
+	return existing, nil
+}
+
+// DeleteProduct soft deletes the product so existing orders still resolve it
+func (p productRepository) DeleteProduct(ctx context.Context, id string) errorPkg.CustomErrors {
+	if p.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	tx := p.db.GetDb().WithContext(ctx).Debug().Where("id=?", id).Delete(&entities.Product{})
+	if tx.Error != nil {
+		logger.Log.Error("Could not delete product: ", tx.Error)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete product.")
+	}
+
+	if tx.RowsAffected == 0 {
+		logger.Log.Warnf("Product with id %v not found.", id)
+		return errorPkg.CustomErrorHandle(http.StatusNotFound, "Product not found.")
+	}
+
+	logger.Log.Infof("Product deleted successfully with ID: %v", id)
+	return nil
+}
@@ -1,13 +1,76 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
 )
 
 type CustomerHandler interface {
-	GetAllCustomers() ([]entities.Customer, errorPkg.CustomErrors)
-	GetCustomerByID(id string) (*entities.Customer, errorPkg.CustomErrors)
-	CreateOrder(customerID string, productIds []string) (*entities.Order, errorPkg.CustomErrors)
-	GetOrderByID(orderId string) (*entities.Order, errorPkg.CustomErrors)
+	GetAllCustomers(ctx context.Context, query entities.CustomerListQuery) (*entities.Page[entities.Customer], errorPkg.CustomErrors)
+	GetCustomerByID(ctx context.Context, id string) (*entities.Customer, errorPkg.CustomErrors)
+	CreateCustomer(ctx context.Context, customer *entities.Customer) (*entities.Customer, errorPkg.CustomErrors)
+	UpdateCustomer(ctx context.Context, id string, customer *entities.Customer) (*entities.Customer, errorPkg.CustomErrors)
+	DeleteCustomer(ctx context.Context, id string) errorPkg.CustomErrors
+	CreateOrder(ctx context.Context, request entities.OrderRequest) (*entities.Order, errorPkg.CustomErrors)
+	QuoteOrder(ctx context.Context, request entities.OrderRequest) (*entities.OrderQuote, errorPkg.CustomErrors)
+	GetOrderByID(ctx context.Context, orderId string) (*entities.Order, errorPkg.CustomErrors)
+	GetOrders(ctx context.Context, query entities.OrderListQuery) (*entities.Page[entities.Order], errorPkg.CustomErrors)
+	UpdateOrderStatus(ctx context.Context, orderId string, status entities.OrderStatus) (*entities.Order, errorPkg.CustomErrors)
+	CancelOrder(ctx context.Context, orderId string, request entities.CancelOrderRequest) (*entities.Order, errorPkg.CustomErrors)
+	GetCustomerOrderLimit(ctx context.Context, customerID string) (*entities.CustomerOrderLimit, errorPkg.CustomErrors)
+	SetCustomerOrderLimit(ctx context.Context, customerID string, limit *entities.CustomerOrderLimit) (*entities.CustomerOrderLimit, errorPkg.CustomErrors)
+	DeleteCustomerOrderLimit(ctx context.Context, customerID string) errorPkg.CustomErrors
+	GetCustomerAddresses(ctx context.Context, customerID string) ([]entities.Address, errorPkg.CustomErrors)
+	CreateAddress(ctx context.Context, customerID string, address *entities.Address) (*entities.Address, errorPkg.CustomErrors)
+	UpdateAddress(ctx context.Context, customerID, addressID string, address *entities.Address) (*entities.Address, errorPkg.CustomErrors)
+	DeleteAddress(ctx context.Context, customerID, addressID string) errorPkg.CustomErrors
+}
+
+type ProductHandler interface {
+	GetAllProducts(ctx context.Context, query entities.ProductListQuery) (*entities.Page[entities.Product], errorPkg.CustomErrors)
+	GetProductByID(ctx context.Context, id string) (*entities.Product, errorPkg.CustomErrors)
+	CreateProduct(ctx context.Context, product *entities.Product) (*entities.Product, errorPkg.CustomErrors)
+	UpdateProduct(ctx context.Context, id string, product *entities.Product) (*entities.Product, errorPkg.CustomErrors)
+	DeleteProduct(ctx context.Context, id string) errorPkg.CustomErrors
+}
+
+type CouponHandler interface {
+	GetAllCoupons(ctx context.Context, query entities.CouponListQuery) (*entities.Page[entities.Coupon], errorPkg.CustomErrors)
+	GetCouponByID(ctx context.Context, id string) (*entities.Coupon, errorPkg.CustomErrors)
+	CreateCoupon(ctx context.Context, coupon *entities.Coupon) (*entities.Coupon, errorPkg.CustomErrors)
+	DeleteCoupon(ctx context.Context, id string) errorPkg.CustomErrors
+}
+
+type PaymentHandler interface {
+	GetOrderPayments(ctx context.Context, orderId string) ([]entities.Payment, errorPkg.CustomErrors)
+	CreatePayment(ctx context.Context, orderId string, request entities.PaymentRequest) (*entities.Payment, errorPkg.CustomErrors)
+	CapturePayment(ctx context.Context, orderId, paymentId string) (*entities.Payment, errorPkg.CustomErrors)
+	VoidPayment(ctx context.Context, orderId, paymentId string) (*entities.Payment, errorPkg.CustomErrors)
+	RefundPayment(ctx context.Context, orderId, paymentId string, request entities.RefundRequest) (*entities.Payment, errorPkg.CustomErrors)
+	ReceivePaymentWebhook(ctx context.Context, provider string, event payment.Event, payload []byte) (*entities.PaymentWebhookEvent, bool, errorPkg.CustomErrors)
+	ReplayPaymentWebhook(ctx context.Context, id string) (*entities.PaymentWebhookEvent, errorPkg.CustomErrors)
+}
+
+type WebhookHandler interface {
+	GetWebhookSubscriptions(ctx context.Context) ([]entities.WebhookSubscription, errorPkg.CustomErrors)
+	GetWebhookSubscriptionByID(ctx context.Context, id string) (*entities.WebhookSubscription, errorPkg.CustomErrors)
+	CreateWebhookSubscription(ctx context.Context, subscription *entities.WebhookSubscription) (*entities.WebhookSubscription, errorPkg.CustomErrors)
+	UpdateWebhookSubscription(ctx context.Context, id string, subscription *entities.WebhookSubscription) (*entities.WebhookSubscription, errorPkg.CustomErrors)
+	DeleteWebhookSubscription(ctx context.Context, id string) errorPkg.CustomErrors
+	GetWebhookDeliveries(ctx context.Context, id string, query entities.WebhookDeliveryListQuery) (*entities.Page[entities.WebhookDelivery], errorPkg.CustomErrors)
+	RetryWebhookDelivery(ctx context.Context, id, deliveryId string) (*entities.WebhookDelivery, errorPkg.CustomErrors)
+	// The delivery queue of the webhook dispatcher
+	webhook.Store
+}
+
+type IdempotencyHandler interface {
+	ReserveIdempotencyKey(ctx context.Context, endpoint, key, fingerprint string, lease time.Duration) (*entities.IdempotencyKey, bool, errorPkg.CustomErrors)
+	CompleteIdempotencyKey(ctx context.Context, endpoint, key string, status int, body []byte, ttl time.Duration) errorPkg.CustomErrors
+	ReleaseIdempotencyKey(ctx context.Context, endpoint, key string) errorPkg.CustomErrors
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, errorPkg.CustomErrors)
 }
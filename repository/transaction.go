@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errDiscard rolls back a unit of work that only looks at what would happen
+var errDiscard = errors.New("unit of work discarded")
+
+// txError returns what a unit of work failed with: the CustomErrors of the
+// repository as they are, anything else, e.g. a failed commit, as a 500
+func txError(err error) errorPkg.CustomErrors {
+	var errs errorPkg.CustomErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+
+	logger.Log.Error("Error commiting transaction: ", err)
+	return errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+}
+
+// forUpdate locks the rows db reads until the transaction ends, unless the
+// unit of work is read only and must not hold up concurrent writers
+func forUpdate(db *gorm.DB, readOnly bool) *gorm.DB {
+	if readOnly {
+		return db
+	}
+	return db.Clauses(clause.Locking{Strength: "UPDATE"})
+}
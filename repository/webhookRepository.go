@@ -0,0 +1,343 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookRepository struct {
+	db database.Database
+}
+
+func NewWebhookRepository(db database.Database) WebhookHandler {
+	return &webhookRepository{db: db}
+}
+
+// webhookDeliverySorts lists the columns deliveries can be sorted on
+var webhookDeliverySorts = timestampSorts(map[string]sortOption[entities.WebhookDelivery]{},
+	func(d entities.WebhookDelivery) entities.BaseModel { return d.BaseModel })
+
+// GetWebhookSubscriptions returns every webhook subscription
+func (wr webhookRepository) GetWebhookSubscriptions(ctx context.Context) ([]entities.WebhookSubscription, errorPkg.CustomErrors) {
+	if wr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	subscriptions := []entities.WebhookSubscription{}
+	if err := wr.db.GetDb().WithContext(ctx).Debug().Order("created_at, id").Find(&subscriptions).Error; err != nil {
+		logger.Log.Error("Error fetching webhook subscriptions: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return subscriptions, nil
+}
+
+// GetWebhookSubscriptionByID retrieves the webhook subscription by provided Id
+func (wr webhookRepository) GetWebhookSubscriptionByID(ctx context.Context, id string) (*entities.WebhookSubscription, errorPkg.CustomErrors) {
+	if wr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	var subscription entities.WebhookSubscription
+	if err := wr.db.GetDb().WithContext(ctx).Debug().Where("id = ?", id).First(&subscription).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Webhook subscription with id %v not found.", id)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Webhook subscription not found.")
+		}
+
+		logger.Log.Error("Error fetching webhook subscription: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return &subscription, nil
+}
+
+// CreateWebhookSubscription subscribes a URL to events, generating a secret
+// when none is given
+func (wr webhookRepository) CreateWebhookSubscription(ctx context.Context, subscription *entities.WebhookSubscription) (*entities.WebhookSubscription, errorPkg.CustomErrors) {
+	if wr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	if subscription.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			logger.Log.Error("Could not generate webhook secret: ", err)
+			return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not generate webhook secret.")
+		}
+		subscription.Secret = secret
+	}
+
+	if err := wr.db.GetDb().WithContext(ctx).Debug().Create(subscription).Error; err != nil {
+		logger.Log.Error("Could not create webhook subscription: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not create webhook subscription.")
+	}
+
+	logger.Log.Infof("Webhook subscription %v created for %v", subscription.ID, subscription.URL)
+	return subscription, nil
+}
+
+// UpdateWebhookSubscription replaces the URL, event types, secret and active
+// flag of a subscription. The secret is kept when none is given.
+func (wr webhookRepository) UpdateWebhookSubscription(ctx context.Context, id string, subscription *entities.WebhookSubscription) (*entities.WebhookSubscription, errorPkg.CustomErrors) {
+	existing, errs := wr.GetWebhookSubscriptionByID(ctx, id)
+	if errs != nil {
+		return nil, errs
+	}
+
+	columns := []interface{}{"event_types", "active"}
+	if subscription.Secret != "" {
+		columns = append(columns, "secret")
+	}
+
+	if err := wr.db.GetDb().WithContext(ctx).Debug().Model(existing).Select("url", columns...).Updates(subscription).Error; err != nil {
+		logger.Log.Error("Could not update webhook subscription: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not update webhook subscription.")
+	}
+
+	logger.Log.Infof("Webhook subscription %v updated", id)
+	return wr.GetWebhookSubscriptionByID(ctx, id)
+}
+
+// DeleteWebhookSubscription removes a subscription; its pending deliveries
+// are no longer sent
+func (wr webhookRepository) DeleteWebhookSubscription(ctx context.Context, id string) errorPkg.CustomErrors {
+	if wr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	result := wr.db.GetDb().WithContext(ctx).Debug().Delete(&entities.WebhookSubscription{}, "id = ?", id)
+	if result.Error != nil {
+		logger.Log.Error("Could not delete webhook subscription: ", result.Error)
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not delete webhook subscription.")
+	}
+
+	if result.RowsAffected == 0 {
+		logger.Log.Warnf("Webhook subscription with id %v not found.", id)
+		return errorPkg.CustomErrorHandle(http.StatusNotFound, "Webhook subscription not found.")
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns one page of the delivery log of a subscription
+func (wr webhookRepository) GetWebhookDeliveries(ctx context.Context, id string, query entities.WebhookDeliveryListQuery) (*entities.Page[entities.WebhookDelivery], errorPkg.CustomErrors) {
+	if _, errs := wr.GetWebhookSubscriptionByID(ctx, id); errs != nil {
+		return nil, errs
+	}
+
+	db := filterTimestamps(wr.db.GetDb().WithContext(ctx).Debug().Model(&entities.WebhookDelivery{}), query.TimestampFilter).Where("subscription_id = ?", id)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+
+	sort := query.Sort
+	if sort == "" {
+		sort = "-created_at"
+	}
+
+	page, err := keysetPage(db, pageRequest{Limit: query.Limit, Cursor: query.Cursor, Sort: sort}, webhookDeliverySorts,
+		func(d entities.WebhookDelivery) string { return d.ID.String() },
+		func(db *gorm.DB) *gorm.DB {
+			return db.Preload("AttemptLog", func(db *gorm.DB) *gorm.DB { return db.Order("created_at") })
+		})
+	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) {
+			logger.Log.Warn("Invalid cursor for listing webhook deliveries")
+			return nil, errorPkg.CustomErrorHandle(http.StatusBadRequest, err.Error())
+		}
+
+		logger.Log.Error("Error fetching webhook deliveries: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	return page, nil
+}
+
+// RetryWebhookDelivery queues a dead delivery again with a fresh set of attempts
+func (wr webhookRepository) RetryWebhookDelivery(ctx context.Context, id, deliveryId string) (*entities.WebhookDelivery, errorPkg.CustomErrors) {
+	if wr.db == nil {
+		logger.Log.Warnf("Database connection not available.")
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Database connection not available")
+	}
+
+	result := wr.db.GetDb().WithContext(ctx).Debug().Model(&entities.WebhookDelivery{}).
+		Where("id = ? AND subscription_id = ? AND status = ?", deliveryId, id, entities.DeliveryDead).
+		Updates(map[string]interface{}{"status": entities.DeliveryPending, "attempts": 0, "next_attempt_at": time.Now()})
+	if result.Error != nil {
+		logger.Log.Error("Could not retry webhook delivery: ", result.Error)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, "Could not retry webhook delivery.")
+	}
+
+	var delivery entities.WebhookDelivery
+	if err := wr.db.GetDb().WithContext(ctx).Debug().Preload("AttemptLog").Where("id = ? AND subscription_id = ?", deliveryId, id).First(&delivery).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Log.Warnf("Webhook delivery %v not found.", deliveryId)
+			return nil, errorPkg.CustomErrorHandle(http.StatusNotFound, "Webhook delivery not found.")
+		}
+
+		logger.Log.Error("Error fetching webhook delivery: ", err)
+		return nil, errorPkg.CustomErrorHandle(http.StatusInternalServerError, err.Error())
+	}
+
+	if result.RowsAffected == 0 {
+		logger.Log.Warnf("Webhook delivery %v is %v and cannot be retried", deliveryId, delivery.Status)
+		return nil, errorPkg.CustomErrorHandle(http.StatusConflict, "Only dead deliveries can be retried.")
+	}
+
+	logger.Log.Infof("Webhook delivery %v queued again", deliveryId)
+	return &delivery, nil
+}
+
+// ClaimDeliveries leases up to limit due deliveries of active subscriptions
+// to the caller. Rows claimed by another dispatcher are skipped.
+func (wr webhookRepository) ClaimDeliveries(now time.Time, limit int, lease time.Duration) ([]webhook.Delivery, error) {
+	var claimed []webhook.Delivery
+
+	err := wr.db.WithTx(context.Background(), func(tx *gorm.DB) error {
+		var deliveries []entities.WebhookDelivery
+		err := tx.Debug().
+			Select("webhook_deliveries.*").
+			Joins("JOIN webhook_subscriptions s ON s.id = webhook_deliveries.subscription_id AND s.deleted_at IS NULL AND s.active").
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", entities.DeliveryPending, now).
+			Order("webhook_deliveries.next_attempt_at").
+			Limit(limit).
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(deliveries))
+		subscriptionIds := make([]uuid.UUID, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID)
+			subscriptionIds = append(subscriptionIds, delivery.SubscriptionID)
+		}
+
+		if err := tx.Debug().Model(&entities.WebhookDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error; err != nil {
+			return err
+		}
+
+		var subscriptions []entities.WebhookSubscription
+		if err := tx.Debug().Where("id IN ?", subscriptionIds).Find(&subscriptions).Error; err != nil {
+			return err
+		}
+
+		subscriptionsById := make(map[uuid.UUID]entities.WebhookSubscription, len(subscriptions))
+		for _, subscription := range subscriptions {
+			subscriptionsById[subscription.ID] = subscription
+		}
+
+		for _, delivery := range deliveries {
+			subscription := subscriptionsById[delivery.SubscriptionID]
+			claimed = append(claimed, webhook.Delivery{
+				ID:        delivery.ID.String(),
+				URL:       subscription.URL,
+				Secret:    subscription.Secret,
+				EventID:   delivery.EventID.String(),
+				EventType: delivery.EventType,
+				Payload:   delivery.Payload,
+				Attempts:  delivery.Attempts,
+			})
+		}
+		return nil
+	})
+
+	return claimed, err
+}
+
+// RecordAttempt logs an attempt of a delivery and moves it on accordingly
+func (wr webhookRepository) RecordAttempt(delivery webhook.Delivery, attempt webhook.Attempt) error {
+	updates := map[string]interface{}{
+		"attempts":         gorm.Expr("attempts + 1"),
+		"last_status_code": attempt.StatusCode,
+		"last_error":       attempt.Error,
+	}
+
+	switch attempt.Outcome {
+	case webhook.Delivered:
+		updates["status"] = entities.DeliveryDelivered
+		updates["delivered_at"] = attempt.AttemptedAt
+	case webhook.Dead:
+		updates["status"] = entities.DeliveryDead
+		logger.Log.Warnf("Webhook delivery %v is dead after %d attempts: %v", delivery.ID, delivery.Attempts+1, attempt.Error)
+	default:
+		updates["next_attempt_at"] = attempt.NextAttemptAt
+	}
+
+	return wr.db.WithTx(context.Background(), func(tx *gorm.DB) error {
+		err := tx.Debug().Create(&entities.WebhookDeliveryAttempt{
+			ID:         uuid.New(),
+			CreatedAt:  attempt.AttemptedAt,
+			DeliveryID: uuid.MustParse(delivery.ID),
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.Duration.Milliseconds(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Debug().Model(&entities.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error
+	})
+}
+
+// enqueueWebhookEvent queues the event for every active subscription to its
+// type within db, so it is only sent if the change it reports is committed
+func enqueueWebhookEvent(db *gorm.DB, eventType string, data interface{}) error {
+	var subscriptions []entities.WebhookSubscription
+	if err := db.Debug().Where("active AND event_types @> ?::jsonb", entities.EventTypeList{eventType}).Find(&subscriptions).Error; err != nil {
+		return err
+	}
+
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	event := entities.WebhookEvent{ID: uuid.New(), Type: eventType, CreatedAt: time.Now(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]entities.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, entities.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      eventType,
+			Payload:        payload,
+			Status:         entities.DeliveryPending,
+			NextAttemptAt:  event.CreatedAt,
+		})
+	}
+
+	return db.Debug().Create(&deliveries).Error
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
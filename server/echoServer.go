@@ -3,12 +3,17 @@ package server
 import (
 	"context"
 	"fmt"
-	"net/http"
+	"net"
 
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/handler"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/shipping"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/tax"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
@@ -20,16 +25,24 @@ type EchoServer struct {
 	app  *echo.Echo
 	db   database.Database
 	conf *config.Config
+	// cancelRequests cancels the context of every request being served
+	cancelRequests context.CancelFunc
 }
 
 func NewEchoServer(conf *config.Config, db database.Database) Server {
 	echoApp := echo.New()
 	echoApp.Logger.SetLevel(log.DEBUG)
+	echoApp.Validator = handler.NewRequestValidator()
+
+	// Requests run on a context of the server so shutting down can cancel them
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	echoApp.Server.BaseContext = func(net.Listener) context.Context { return requestCtx }
 
 	return &EchoServer{
-		app:  echoApp,
-		db:   db,
-		conf: conf,
+		app:            echoApp,
+		db:             db,
+		conf:           conf,
+		cancelRequests: cancelRequests,
 	}
 }
 
@@ -37,14 +50,13 @@ func (s *EchoServer) Start() error {
 	s.app.Use(middleware.Recover())
 	s.app.Use(middleware.Logger())
 	s.app.Use(handler.LatencyLogger)
+	s.app.Use(handler.RequestTimeout(s.conf.Server.RequestTimeout, s.conf.Server.RouteTimeouts))
 
 	//initialize logger
 	logger.Init()
 
 	// Health check adding
-	s.app.GET("/v1/health", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, "Ok")
-	})
+	s.app.GET("/v1/health", handler.Health(s.db))
 
 	//initialize routes
 	s.Routes()
@@ -53,21 +65,85 @@ func (s *EchoServer) Start() error {
 	return s.app.Start(serverUrl)
 }
 
-// Shutdown gracefully stops the server with a given context
+// Shutdown gracefully stops the server with a given context. Requests still
+// running when ctx is done have their queries cancelled.
 func (s *EchoServer) Shutdown(ctx context.Context) error {
 	logrus.Println("Attempting to gracefully shutdown the server...")
+
+	stop := context.AfterFunc(ctx, s.cancelRequests)
+	defer stop()
+
 	return s.app.Shutdown(ctx)
 }
 
 // Routes define the new routes
 func (s *EchoServer) Routes() {
-	customerRepo := repository.NewCustomerRepository(s.db)
+	payments := payment.NewRegistry(payment.NewFakeProvider())
+	customerRepo := repository.NewCustomerRepository(s.db, policy.NewEngine(s.conf.OrderPolicy), tax.NewEngine(s.conf.Tax), shipping.NewEngine(s.conf.Shipping), payments)
 	customerHandler := handler.NewCustomerHandler(customerRepo)
+	idempotencyRepo := repository.NewIdempotencyRepository(s.db)
 
 	route := s.app.Group("/api")
 
 	route.GET("/customers", customerHandler.GetAllCustomers)
 	route.GET("/customers/:id", customerHandler.GetCustomerByID)
-	route.POST("/orders", customerHandler.CreateOrder)
+	route.POST("/customers", customerHandler.CreateCustomer)
+	route.PUT("/customers/:id", customerHandler.UpdateCustomer)
+	route.PATCH("/customers/:id", customerHandler.PatchCustomer)
+	route.DELETE("/customers/:id", customerHandler.DeleteCustomer)
+	route.GET("/customers/:id/orders", customerHandler.GetCustomerOrders)
+	route.GET("/customers/:id/order-limits", customerHandler.GetCustomerOrderLimit)
+	route.PUT("/customers/:id/order-limits", customerHandler.SetCustomerOrderLimit)
+	route.DELETE("/customers/:id/order-limits", customerHandler.DeleteCustomerOrderLimit)
+	route.GET("/customers/:id/addresses", customerHandler.GetCustomerAddresses)
+	route.POST("/customers/:id/addresses", customerHandler.CreateAddress)
+	route.PUT("/customers/:id/addresses/:addressId", customerHandler.UpdateAddress)
+	route.DELETE("/customers/:id/addresses/:addressId", customerHandler.DeleteAddress)
+	route.GET("/orders", customerHandler.GetOrders)
+	route.POST("/orders", customerHandler.CreateOrder, handler.Idempotency(idempotencyRepo, s.conf.Idempotency.KeyTTL, s.conf.Idempotency.InFlightLease))
+	route.POST("/orders/quote", customerHandler.QuoteOrder)
 	route.GET("/orders/:id", customerHandler.GetOrderByID)
+	route.PATCH("/orders/:id/status", customerHandler.UpdateOrderStatus)
+	route.POST("/orders/:id/cancel", customerHandler.CancelOrder)
+
+	paymentRepo := repository.NewPaymentRepository(s.db, payments, s.conf.Payments.Provider)
+	paymentHandler := handler.NewPaymentHandler(paymentRepo, payment.NewWebhookVerifier(s.conf.Payments.WebhookSecrets, s.conf.Payments.WebhookTolerance))
+
+	route.GET("/orders/:id/payments", paymentHandler.GetOrderPayments)
+	route.POST("/orders/:id/payments", paymentHandler.CreatePayment)
+	route.POST("/orders/:id/payments/:paymentId/capture", paymentHandler.CapturePayment)
+	route.POST("/orders/:id/payments/:paymentId/void", paymentHandler.VoidPayment)
+	route.POST("/orders/:id/payments/:paymentId/refunds", paymentHandler.RefundPayment)
+	route.POST("/webhooks/payments/events/:id/replay", paymentHandler.ReplayPaymentWebhook)
+
+	// Providers call webhooks directly, they are authenticated by their signature
+	s.app.POST("/webhooks/payments/:provider", paymentHandler.ReceivePaymentWebhook)
+
+	productRepo := repository.NewProductRepository(s.db)
+	productHandler := handler.NewProductHandler(productRepo)
+
+	route.GET("/products", productHandler.GetAllProducts)
+	route.GET("/products/:id", productHandler.GetProductByID)
+	route.POST("/products", productHandler.CreateProduct)
+	route.PUT("/products/:id", productHandler.UpdateProduct)
+	route.DELETE("/products/:id", productHandler.DeleteProduct)
+
+	couponRepo := repository.NewCouponRepository(s.db)
+	couponHandler := handler.NewCouponHandler(couponRepo)
+
+	route.GET("/coupons", couponHandler.GetAllCoupons)
+	route.GET("/coupons/:id", couponHandler.GetCouponByID)
+	route.POST("/coupons", couponHandler.CreateCoupon)
+	route.DELETE("/coupons/:id", couponHandler.DeleteCoupon)
+
+	webhookRepo := repository.NewWebhookRepository(s.db)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo, webhook.NewURLGuard(s.conf.Webhooks.AllowPrivateNetworks))
+
+	route.GET("/webhooks", webhookHandler.GetWebhookSubscriptions)
+	route.GET("/webhooks/:id", webhookHandler.GetWebhookSubscriptionByID)
+	route.POST("/webhooks", webhookHandler.CreateWebhookSubscription)
+	route.PUT("/webhooks/:id", webhookHandler.UpdateWebhookSubscription)
+	route.DELETE("/webhooks/:id", webhookHandler.DeleteWebhookSubscription)
+	route.GET("/webhooks/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+	route.POST("/webhooks/:id/deliveries/:deliveryId/retry", webhookHandler.RetryWebhookDelivery)
 }
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// createShippingAddress adds a default shipping address in India to the
+// customer and returns its id
+func createShippingAddress(t *testing.T, customerID string) string {
+	addressPayloadJSON, _ := json.Marshal(entities.AddressRequest{
+		Recipient:         "Ganesh",
+		Line1:             "12 MG Road",
+		City:              "Pune",
+		PostalCode:        "411001",
+		Country:           "India",
+		IsDefaultShipping: true,
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers/"+customerID+"/addresses", "application/json", bytes.NewBuffer(addressPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var address entities.Address
+	if err := json.NewDecoder(resp.Body).Decode(&address); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	return address.ID.String()
+}
+
+// Test case for a customer's addresses and the default shipping flag
+func TestCustomerAddresses(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Address Customer",
+		Email: "address." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	first := createShippingAddress(t, customer.ID.String())
+	second := createShippingAddress(t, customer.ID.String())
+
+	resp, err = http.Get("http://localhost:8080/api/customers/" + customer.ID.String() + "/addresses")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var addresses []entities.Address
+	if err := json.NewDecoder(resp.Body).Decode(&addresses); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// The newer default took the flag over from the first address
+	assert.Len(t, addresses, 2)
+	for _, address := range addresses {
+		assert.Equal(t, address.ID.String() == second, address.IsDefaultShipping)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/api/customers/"+customer.ID.String()+"/addresses/"+first, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// A deleted address cannot be shipped to
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: first,
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders/quote", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+}
+
+// Test case for concurrent requests each taking over the default address
+func TestConcurrentDefaultAddresses(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Concurrent Address Customer",
+		Email: "address." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressPayloadJSON, _ := json.Marshal(entities.AddressRequest{
+		Recipient:         "Ganesh",
+		Line1:             "12 MG Road",
+		City:              "Pune",
+		PostalCode:        "411001",
+		Country:           "India",
+		IsDefaultShipping: true,
+	})
+
+	statusCodes := make([]int, 5)
+	var wg sync.WaitGroup
+	for i := range statusCodes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			resp, err := http.Post("http://localhost:8080/api/customers/"+customer.ID.String()+"/addresses", "application/json", bytes.NewBuffer(addressPayloadJSON))
+			if err != nil {
+				t.Errorf("Failed to send request: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+
+			statusCodes[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+
+	// The requests queue up on the customer instead of failing
+	for _, statusCode := range statusCodes {
+		assert.Equal(t, http.StatusCreated, statusCode)
+	}
+
+	resp, err = http.Get("http://localhost:8080/api/customers/" + customer.ID.String() + "/addresses")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var addresses []entities.Address
+	if err := json.NewDecoder(resp.Body).Decode(&addresses); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// Exactly one address is left as the default
+	defaults := 0
+	for _, address := range addresses {
+		if address.IsDefaultShipping {
+			defaults++
+		}
+	}
+	assert.Len(t, addresses, 5)
+	assert.Equal(t, 1, defaults)
+}
@@ -0,0 +1,70 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for the discount of every coupon type
+func TestCouponDiscount(t *testing.T) {
+	lines := []entities.CouponLine{
+		{Category: "Stationery", Quantity: 3, UnitPrice: money.New(1000, "INR")},
+		{Category: "Books", Quantity: 1, UnitPrice: money.New(2555, "INR")},
+	}
+
+	testCases := []struct {
+		name     string
+		coupon   entities.Coupon
+		discount money.Money
+	}{
+		{"percentage", entities.Coupon{Type: entities.CouponPercentage, PercentOff: 10}, money.New(556, "INR")},
+		{"fixed amount", entities.Coupon{Type: entities.CouponFixedAmount, AmountOff: 500, Currency: "INR"}, money.New(500, "INR")},
+		{"fixed amount capped at the basket", entities.Coupon{Type: entities.CouponFixedAmount, AmountOff: 100000, Currency: "INR"}, money.New(5555, "INR")},
+		{"category", entities.Coupon{Type: entities.CouponPercentage, PercentOff: 50, Category: "books"}, money.New(1278, "INR")},
+		{"buy 2 get 1", entities.Coupon{Type: entities.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1}, money.New(1000, "INR")},
+		{"minimum basket met", entities.Coupon{Type: entities.CouponFixedAmount, AmountOff: 100, MinBasket: 5555, Currency: "INR"}, money.New(100, "INR")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			discount, err := tc.coupon.Discount(lines)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.discount, discount)
+		})
+	}
+}
+
+// Test case for coupons that cannot be applied to an order
+func TestCouponNotApplicable(t *testing.T) {
+	lines := []entities.CouponLine{{Category: "Stationery", Quantity: 2, UnitPrice: money.New(1000, "INR")}}
+
+	coupons := map[string]entities.Coupon{
+		"other category":   {Type: entities.CouponPercentage, PercentOff: 10, Category: "Books"},
+		"basket too small": {Type: entities.CouponPercentage, PercentOff: 10, MinBasket: 5000, Currency: "INR"},
+		"other currency":   {Type: entities.CouponFixedAmount, AmountOff: 100, Currency: "USD"},
+		"too few units":    {Type: entities.CouponBuyXGetY, BuyQuantity: 2, GetQuantity: 1},
+	}
+
+	for name, coupon := range coupons {
+		t.Run(name, func(t *testing.T) {
+			_, err := coupon.Discount(lines)
+			assert.True(t, errors.Is(err, entities.ErrCouponNotApplicable))
+		})
+	}
+
+	// Lines of other categories do not count towards the minimum basket
+	mixed := append(lines, entities.CouponLine{Category: "Books", Quantity: 1, UnitPrice: money.New(2000, "INR")})
+	_, err := entities.Coupon{Type: entities.CouponPercentage, PercentOff: 10, Category: "Books", MinBasket: 3000, Currency: "INR"}.Discount(mixed)
+	assert.True(t, errors.Is(err, entities.ErrCouponNotApplicable))
+
+	now := time.Now()
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+	assert.Error(t, entities.Coupon{StartsAt: &later}.CheckValidAt(now))
+	assert.Error(t, entities.Coupon{EndsAt: &earlier}.CheckValidAt(now))
+	assert.NoError(t, entities.Coupon{StartsAt: &earlier, EndsAt: &later}.CheckValidAt(now))
+}
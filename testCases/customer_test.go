@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,13 +25,65 @@ func TestGetAllCustomers(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
 	// You can also check the response body if needed
-	var customers []map[string]interface{}
+	var customers entities.Page[map[string]interface{}]
 	if err := json.NewDecoder(resp.Body).Decode(&customers); err != nil {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
 	// Example assertion: Check if there is at least one customer
-	assert.True(t, len(customers) > 0)
+	assert.True(t, len(customers.Items) > 0)
+	assert.Equal(t, int64(len(customers.Items)), min(customers.TotalCount, 20))
+}
+
+// Test case for walking the customer listing one page at a time
+func TestGetAllCustomersPagination(t *testing.T) {
+	seen := map[string]bool{}
+	cursor := ""
+
+	for {
+		resp, err := http.Get("http://localhost:8080/api/customers?limit=1&sort=-email&cursor=" + cursor)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page entities.Page[entities.Customer]
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		for _, customer := range page.Items {
+			assert.False(t, seen[customer.ID.String()], "customer returned twice")
+			seen[customer.ID.String()] = true
+		}
+
+		if page.NextCursor == "" {
+			assert.Equal(t, page.TotalCount, int64(len(seen)))
+			break
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// Test case for an empty customer listing
+func TestGetAllCustomersNoMatch(t *testing.T) {
+	resp, err := http.Get("http://localhost:8080/api/customers?country=Atlantis")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page entities.Page[entities.Customer]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	assert.Empty(t, page.Items)
+	assert.Equal(t, int64(0), page.TotalCount)
 }
 
 // Test case for GetCustomerByID endpoint
@@ -64,8 +117,13 @@ func TestGetCustomerByID(t *testing.T) {
 func TestCreateOrder(t *testing.T) {
 	// Prepare a sample order payload
 	orderPayload := entities.OrderRequest{
-		CustomerID: "10ac6f2c-18ae-46da-9cca-4f36c84ce381", // Replace with a valid customer ID
-		ProductIDs: []string{"11ac5f2d-18ea-46ad-9cca-3f36c84ce123", "22ac5f2d-18ea-46ad-9cca-3f36c84ce103", "33ac5f2d-18ea-46ad-9cca-3f36c84ce103"},
+		CustomerID:        "10ac6f2c-18ae-46da-9cca-4f36c84ce381", // Replace with a valid customer ID
+		ShippingAddressID: "44ac6f2c-18ae-46da-9cca-4f36c84ce381", // Replace with a valid address ID of the customer
+		Items: []entities.OrderItemRequest{
+			{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1},
+			{ProductID: "22ac5f2d-18ea-46ad-9cca-3f36c84ce103", Quantity: 1},
+			{ProductID: "33ac5f2d-18ea-46ad-9cca-3f36c84ce103", Quantity: 1},
+		},
 	}
 
 	orderPayloadJSON, _ := json.Marshal(orderPayload)
@@ -103,7 +161,21 @@ func TestCreateOrder(t *testing.T) {
 	}
 
 	// Example assertion: Check if the order total price exists in the response
-	assert.Equal(t, 77.35, order.TotalPrice)
+	assert.Equal(t, money.New(7735, "INR"), order.Subtotal)
+
+	// Without a coupon the total is the subtotal plus any tax of the customer's country and shipping
+	expectedTotal := order.Subtotal
+	if !order.PricesIncludeTax {
+		expectedTotal, _ = expectedTotal.Add(order.TaxTotal)
+	}
+	expectedTotal, _ = expectedTotal.Add(order.ShippingCost)
+	assert.Equal(t, expectedTotal, order.TotalPrice)
+
+	// Every line carries the price charged at the time of the order
+	assert.Len(t, order.Items, 3)
+	for _, item := range order.Items {
+		assert.Equal(t, item.UnitPrice.Multiply(int64(item.Quantity)), item.LineTotal)
+	}
 }
 
 // Test case for GetOrderById endpoint
@@ -137,3 +209,93 @@ func TestGetOrderByID(t *testing.T) {
 		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 	}
 }
+
+// Test case for CreateCustomer, duplicate email rejection and DeleteCustomer
+func TestCreateAndDeleteCustomer(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:    "Test Customer",
+		Email:   "Test.Customer@example.com",
+		Country: "India",
+	})
+
+	// Send POST request to create customer
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, "test.customer@example.com", customer.Email)
+
+	// The same email cannot be registered twice
+	resp, err = http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// Send DELETE request for the customer without orders
+	req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/api/customers/"+customer.ID.String(), nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
+
+// Test case for CreateCustomer rejecting a malformed email
+func TestCreateCustomerInvalidEmail(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Test Customer",
+		Email: "not-an-email",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// Test case for CreateOrder rejecting unknown, duplicate and missing products
+func TestCreateOrderInvalidItems(t *testing.T) {
+	customerID := "10ac6f2c-18ae-46da-9cca-4f36c84ce381" // Replace with a valid customer ID
+	addressID := "44ac6f2c-18ae-46da-9cca-4f36c84ce381"  // Replace with a valid address ID of the customer
+	productID := "11ac5f2d-18ea-46ad-9cca-3f36c84ce123"  // Replace with a valid product ID
+
+	testCases := []struct {
+		name       string
+		items      []entities.OrderItemRequest
+		statusCode int
+	}{
+		{"no items", []entities.OrderItemRequest{}, http.StatusBadRequest},
+		{"unknown product", []entities.OrderItemRequest{{ProductID: "00000000-0000-4000-8000-000000000000", Quantity: 1}}, http.StatusUnprocessableEntity},
+		{"duplicate product", []entities.OrderItemRequest{{ProductID: productID, Quantity: 1}, {ProductID: productID, Quantity: 2}}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{CustomerID: customerID, ShippingAddressID: addressID, Items: tc.items})
+
+			resp, err := http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+			if err != nil {
+				t.Fatalf("Failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.statusCode, resp.StatusCode)
+		})
+	}
+}
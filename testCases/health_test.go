@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/handler"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for reporting database reachability and pool statistics
+func TestHealth(t *testing.T) {
+	logger.Init()
+
+	db, tracker := newTrackedDatabase(t)
+	sqlDb, _ := db.GetDb().DB()
+	sqlDb.SetMaxOpenConns(5)
+
+	app := echo.New()
+	app.GET("/v1/health", handler.Health(db))
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report handler.HealthReport
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, "ok", report.Database.Status)
+	assert.Equal(t, 5, report.Database.MaxOpenConnections)
+	assert.Equal(t, 1, report.Database.OpenConnections)
+	assert.Equal(t, 1, report.Database.Idle)
+
+	tracker.fail = errors.New("connection refused")
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	report = handler.HealthReport{}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, "unavailable", report.Status)
+	assert.Equal(t, "database unavailable", report.Database.Error)
+}
@@ -0,0 +1,208 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/handler"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/errorPkg"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyKeys keeps idempotency keys in memory, failing to store
+// the given number of responses
+type memoryIdempotencyKeys struct {
+	mu           sync.Mutex
+	keys         map[string]*entities.IdempotencyKey
+	now          time.Time
+	failComplete int
+}
+
+func (m *memoryIdempotencyKeys) ReserveIdempotencyKey(ctx context.Context, endpoint, key, fingerprint string, lease time.Duration) (*entities.IdempotencyKey, bool, errorPkg.CustomErrors) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, ok := m.keys[endpoint+key]; ok && existing.ExpiresAt.After(m.now) {
+		return existing, false, nil
+	}
+
+	m.keys[endpoint+key] = &entities.IdempotencyKey{Endpoint: endpoint, Key: key, Fingerprint: fingerprint, CreatedAt: m.now, ExpiresAt: m.now.Add(lease)}
+	return m.keys[endpoint+key], true, nil
+}
+
+func (m *memoryIdempotencyKeys) CompleteIdempotencyKey(ctx context.Context, endpoint, key string, status int, body []byte, ttl time.Duration) errorPkg.CustomErrors {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failComplete > 0 {
+		m.failComplete--
+		return errorPkg.CustomErrorHandle(http.StatusInternalServerError, "connection reset")
+	}
+
+	record := m.keys[endpoint+key]
+	record.ResponseStatus, record.ResponseBody, record.ExpiresAt = &status, body, m.now.Add(ttl)
+	return nil
+}
+
+func (m *memoryIdempotencyKeys) ReleaseIdempotencyKey(ctx context.Context, endpoint, key string) errorPkg.CustomErrors {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if record, ok := m.keys[endpoint+key]; ok && !record.IsComplete() {
+		delete(m.keys, endpoint+key)
+	}
+	return nil
+}
+
+func (m *memoryIdempotencyKeys) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, errorPkg.CustomErrors) {
+	return 0, nil
+}
+
+// Test case for retrying CreateOrder with the same Idempotency-Key
+func TestCreateOrderIdempotencyKey(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Idempotent Customer",
+		Email: "idempotent." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressID := createShippingAddress(t, customer.ID.String())
+
+	key := uuid.NewString()
+	postOrder := func(quantity int) *http.Response {
+		orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+			CustomerID:        customer.ID.String(),
+			ShippingAddressID: addressID,
+			Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: quantity}}, // Replace with a valid product ID
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/api/orders", bytes.NewBuffer(orderPayloadJSON))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", key)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	first := postOrder(1)
+	defer first.Body.Close()
+	assert.Equal(t, http.StatusCreated, first.StatusCode)
+
+	var order entities.Order
+	if err := json.NewDecoder(first.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// The retry replays the original order instead of creating another one
+	retry := postOrder(1)
+	defer retry.Body.Close()
+	assert.Equal(t, http.StatusCreated, retry.StatusCode)
+	assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+
+	var replayed entities.Order
+	if err := json.NewDecoder(retry.Body).Decode(&replayed); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, order.ID, replayed.ID)
+
+	// The same key cannot be used for a different order
+	changed := postOrder(2)
+	defer changed.Body.Close()
+	assert.Equal(t, http.StatusUnprocessableEntity, changed.StatusCode)
+}
+
+// Test case for reserving a key without sweeping the whole table, taking
+// over the key in place only when it expired
+func TestReserveIdempotencyKeyStatements(t *testing.T) {
+	db, tracker := newTrackedDatabase(t)
+	keys := repository.NewIdempotencyRepository(db)
+
+	_, created, errs := keys.ReserveIdempotencyKey(context.Background(), "POST /api/orders", "key-1", "fingerprint", time.Hour)
+	assert.Nil(t, errs)
+	assert.True(t, created)
+
+	if assert.Len(t, tracker.statements, 1) {
+		assert.Contains(t, tracker.statements[0], `ON CONFLICT ("endpoint","key") DO UPDATE SET`)
+		assert.Contains(t, tracker.statements[0], `WHERE "idempotency_keys"."expires_at" <=`)
+	}
+
+	deleted, errs := keys.DeleteExpiredIdempotencyKeys(context.Background(), time.Now())
+	assert.Nil(t, errs)
+	assert.Equal(t, int64(1), deleted)
+	assert.Contains(t, tracker.statements[1], `DELETE FROM "idempotency_keys" WHERE expires_at <=`)
+}
+
+// Test case for releasing a key only when its request failed, and holding
+// it for the in-flight lease when a successful response cannot be stored
+func TestIdempotencyKeySettlement(t *testing.T) {
+	logger.Init()
+
+	keys := &memoryIdempotencyKeys{keys: map[string]*entities.IdempotencyKey{}, now: time.Now()}
+	status, calls := http.StatusInternalServerError, 0
+
+	app := echo.New()
+	app.POST("/orders", func(c echo.Context) error {
+		calls++
+		if calls == 1 {
+			return errors.New("handler failed")
+		}
+		return c.JSON(status, calls)
+	}, handler.Idempotency(keys, time.Hour, time.Minute))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBufferString(`{}`))
+		req.Header.Set(handler.IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A handler error and a server error both free the key for a retry
+	send()
+	assert.Equal(t, http.StatusInternalServerError, send().Code)
+	assert.Equal(t, 2, calls)
+
+	// The order was placed but its response could not be stored: retries
+	// are turned away until the lease ends instead of placing it again
+	status, keys.failComplete = http.StatusCreated, 3
+	assert.Equal(t, http.StatusCreated, send().Code)
+	assert.Equal(t, http.StatusConflict, send().Code)
+	assert.Equal(t, 3, calls)
+
+	keys.now = keys.now.Add(2 * time.Minute)
+
+	// Storing the response is retried when it fails once
+	keys.failComplete = 1
+	assert.Equal(t, http.StatusCreated, send().Code)
+	assert.Equal(t, 4, calls)
+
+	replayed := send()
+	assert.Equal(t, http.StatusCreated, replayed.Code)
+	assert.Equal(t, "true", replayed.Header().Get(handler.IdempotentReplayedHeader))
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for two simultaneous orders competing for the last unit in stock
+func TestConcurrentOrdersCannotOversell(t *testing.T) {
+	stock := 1
+	price := money.New(999, "INR")
+	productPayloadJSON, _ := json.Marshal(entities.ProductRequest{
+		Name:     "Last Unit",
+		Category: "Inventory",
+		Price:    &price,
+		Stock:    &stock,
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/products", "application/json", bytes.NewBuffer(productPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var product entities.Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// Each order comes from a different customer so only stock can stop it
+	var customerIDs, addressIDs []string
+	for i := 0; i < 2; i++ {
+		customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+			Name:  "Stock Customer",
+			Email: fmt.Sprintf("stock-%d-%d@example.com", time.Now().UnixNano(), i),
+		})
+
+		resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var customer entities.Customer
+		if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+		customerIDs = append(customerIDs, customer.ID.String())
+		addressIDs = append(addressIDs, createShippingAddress(t, customer.ID.String()))
+	}
+
+	statusCodes := make([]int, len(customerIDs))
+	var wg sync.WaitGroup
+	for i, customerID := range customerIDs {
+		wg.Add(1)
+		go func(i int, customerID string) {
+			defer wg.Done()
+
+			orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+				CustomerID:        customerID,
+				ShippingAddressID: addressIDs[i],
+				Items:             []entities.OrderItemRequest{{ProductID: product.ID.String(), Quantity: 1}},
+			})
+
+			resp, err := http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+			if err != nil {
+				t.Errorf("Failed to send request: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+
+			statusCodes[i] = resp.StatusCode
+		}(i, customerID)
+	}
+	wg.Wait()
+
+	// Exactly one order gets the unit, the other is told it is unavailable
+	assert.ElementsMatch(t, []int{http.StatusCreated, http.StatusConflict}, statusCodes)
+
+	resp, err = http.Get("http://localhost:8080/api/products/" + product.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, 0, *product.Stock)
+}
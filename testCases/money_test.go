@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for money arithmetic in minor units
+func TestMoneyArithmetic(t *testing.T) {
+	// 0.1 + 0.2 is exact when stored in minor units
+	sum, err := money.FromMajor(0.1, "usd").Add(money.FromMajor(0.2, "USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(30, "USD"), sum)
+
+	assert.Equal(t, money.New(3750, "INR"), money.New(1250, "INR").Multiply(3))
+	assert.Equal(t, "37.50 INR", money.New(3750, "INR").String())
+	assert.Equal(t, "1500 JPY", money.FromMajor(1500, "JPY").String())
+	assert.Equal(t, "1.250 KWD", money.FromMajor(1.25, "KWD").String())
+
+	// Amounts in different currencies cannot be added
+	_, err = money.New(100, "USD").Add(money.New(100, "EUR"))
+	assert.True(t, errors.Is(err, money.ErrCurrencyMismatch))
+}
+
+// Test case for splitting an amount without losing minor units
+func TestMoneyAllocate(t *testing.T) {
+	parts := money.New(100, "INR").Allocate([]int64{1, 1, 1})
+	assert.Equal(t, []money.Money{money.New(34, "INR"), money.New(33, "INR"), money.New(33, "INR")}, parts)
+
+	// Lines with no weight get nothing
+	parts = money.New(500, "INR").Allocate([]int64{3000, 0, 1000})
+	assert.Equal(t, []money.Money{money.New(375, "INR"), money.New(0, "INR"), money.New(125, "INR")}, parts)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for cancelling an order and placing the next one
+func TestCancelOrder(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Cancelling Customer",
+		Email: "cancel." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressID := createShippingAddress(t, customer.ID.String())
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: addressID,
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var order entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	cancelPayloadJSON, _ := json.Marshal(entities.CancelOrderRequest{
+		Reason:      entities.ReasonDuplicateOrder,
+		Note:        "Placed twice by mistake",
+		CancelledBy: entities.CancelledByCustomer,
+	})
+	cancel := func() *http.Response {
+		resp, err := http.Post("http://localhost:8080/api/orders/"+order.ID.String()+"/cancel", "application/json", bytes.NewBuffer(cancelPayloadJSON))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	resp = cancel()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var cancelled entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.Cancelled, cancelled.Status)
+	if assert.NotNil(t, cancelled.CancelReason) {
+		assert.Equal(t, entities.ReasonDuplicateOrder, *cancelled.CancelReason)
+	}
+	assert.NotNil(t, cancelled.CancelledAt)
+
+	// A cancelled order cannot be cancelled again
+	resp = cancel()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+	// The customer is no longer blocked by the cancelled order
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
@@ -0,0 +1,65 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for the order status transition table
+func TestOrderStatusTransitions(t *testing.T) {
+	allowed := map[entities.OrderStatus][]entities.OrderStatus{
+		entities.Pending:   {entities.Paid, entities.Cancelled},
+		entities.Paid:      {entities.Picking, entities.Cancelled},
+		entities.Picking:   {entities.Shipped, entities.Cancelled},
+		entities.Shipped:   {entities.Delivered},
+		entities.Delivered: {entities.Returned},
+	}
+
+	for _, from := range entities.OrderStatuses {
+		for _, to := range entities.OrderStatuses {
+			expected := false
+			for _, status := range allowed[from] {
+				if status == to {
+					expected = true
+				}
+			}
+			assert.Equal(t, expected, from.CanTransitionTo(to), "%v -> %v", from, to)
+		}
+	}
+
+	// Terminal statuses no longer block new orders
+	assert.True(t, entities.Shipped.IsOpen())
+	assert.False(t, entities.Delivered.IsOpen())
+	assert.False(t, entities.Cancelled.IsOpen())
+	assert.False(t, entities.Returned.IsOpen())
+	assert.False(t, entities.OrderStatus("unfulfilled").IsValid())
+}
+
+// Test case for UpdateOrderStatus endpoint rejecting an illegal transition
+func TestUpdateOrderStatusIllegalTransition(t *testing.T) {
+	orderId := "10ac6f2c-18ae-46da-9cca-4f36c84ce343" // Replace with an actual pending order ID in your database
+
+	payload, _ := json.Marshal(entities.OrderStatusRequest{Status: entities.Delivered})
+
+	req, err := http.NewRequest(http.MethodPatch, "http://localhost:8080/api/orders/"+orderId+"/status", bytes.NewBuffer(payload))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// A pending order cannot jump straight to delivered
+	if resp.StatusCode != http.StatusNotFound {
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	}
+}
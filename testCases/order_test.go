@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for GetOrders filtering by status and walking the pages
+func TestGetOrdersByStatus(t *testing.T) {
+	var previous *entities.Order
+	cursor := ""
+
+	for {
+		resp, err := http.Get("http://localhost:8080/api/orders?status=pending,paid&sort=-total&limit=2&include=items&cursor=" + cursor)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var page entities.Page[entities.Order]
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		for i := range page.Items {
+			order := page.Items[i]
+			assert.Contains(t, []entities.OrderStatus{entities.Pending, entities.Paid}, order.Status)
+			assert.NotEmpty(t, order.Items)
+
+			// Orders come back with the highest total first
+			if previous != nil {
+				assert.LessOrEqual(t, order.TotalPrice.Amount, previous.TotalPrice.Amount)
+			}
+			previous = &order
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// Test case for GetOrders rejecting an unknown status
+func TestGetOrdersInvalidStatus(t *testing.T) {
+	resp, err := http.Get("http://localhost:8080/api/orders?status=lost")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// Test case for GetOrders filtering by total, which needs the currency the
+// totals are in
+func TestGetOrdersByTotal(t *testing.T) {
+	resp, err := http.Get("http://localhost:8080/api/orders?min_total=1000")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get("http://localhost:8080/api/orders?min_total=1000&max_total=500000&currency=inr&limit=100")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var page entities.Page[entities.Order]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	for _, order := range page.Items {
+		assert.Equal(t, "INR", order.TotalPrice.Currency)
+		assert.GreaterOrEqual(t, order.TotalPrice.Amount, int64(1000))
+		assert.LessOrEqual(t, order.TotalPrice.Amount, int64(500000))
+	}
+}
+
+// Test case for GetCustomerOrders endpoint
+func TestGetCustomerOrders(t *testing.T) {
+	customerID := "10ac6f2c-18ae-46da-9cca-4f36c84ce381" // Replace with an actual customer ID in your database
+
+	resp, err := http.Get("http://localhost:8080/api/customers/" + customerID + "/orders")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		var page entities.Page[entities.Order]
+		if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+			t.Fatalf("Failed to parse response: %v", err)
+		}
+
+		for _, order := range page.Items {
+			assert.Equal(t, customerID, order.CustomerID.String())
+		}
+	} else {
+		// Assert not found if status is 404
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+}
@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/outbox"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryOutboxStore hands out every unpublished message that is due
+type memoryOutboxStore struct {
+	messages  []outbox.Message
+	published map[string]bool
+	retryAt   map[string]time.Time
+}
+
+func (m *memoryOutboxStore) ClaimMessages(now time.Time, limit int, lease time.Duration) ([]outbox.Message, error) {
+	var claimed []outbox.Message
+	for _, message := range m.messages {
+		if !m.published[message.ID] && !m.retryAt[message.ID].After(now) {
+			claimed = append(claimed, message)
+		}
+	}
+	return claimed, nil
+}
+
+func (m *memoryOutboxStore) MarkPublished(message outbox.Message, at time.Time) error {
+	m.published[message.ID] = true
+	return nil
+}
+
+func (m *memoryOutboxStore) MarkFailed(message outbox.Message, reason string, retryAt time.Time) error {
+	m.retryAt[message.ID] = retryAt
+	for i := range m.messages {
+		if m.messages[i].ID == message.ID {
+			m.messages[i].Attempts++
+		}
+	}
+	return nil
+}
+
+// leasingOutboxStore leases claimed messages and keeps every aggregate in
+// order the way the outbox table does
+type leasingOutboxStore struct {
+	*leasedQueue[outbox.Message]
+}
+
+func newLeasingOutboxStore(messages ...outbox.Message) leasingOutboxStore {
+	return leasingOutboxStore{newLeasedQueue(
+		func(message outbox.Message) string { return message.ID },
+		func(earlier, message outbox.Message) bool { return earlier.AggregateID == message.AggregateID },
+		messages...,
+	)}
+}
+
+func (s leasingOutboxStore) ClaimMessages(now time.Time, limit int, lease time.Duration) ([]outbox.Message, error) {
+	return s.claim(now, limit, lease), nil
+}
+
+func (s leasingOutboxStore) MarkPublished(message outbox.Message, at time.Time) error {
+	s.finish(message)
+	return nil
+}
+
+func (s leasingOutboxStore) MarkFailed(message outbox.Message, reason string, retryAt time.Time) error {
+	s.retry(message, retryAt)
+	return nil
+}
+
+// slowPublisher takes a while to accept every message
+type slowPublisher struct {
+	mu       sync.Mutex
+	delay    time.Duration
+	received []string
+}
+
+func (p *slowPublisher) Publish(ctx context.Context, message outbox.Message) error {
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.received = append(p.received, message.ID)
+	return nil
+}
+
+// flakyPublisher fails the first message of an aggregate once
+type flakyPublisher struct {
+	outbox.MemoryPublisher
+	failAggregate string
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, message outbox.Message) error {
+	if message.AggregateID == p.failAggregate {
+		p.failAggregate = ""
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(ctx, message)
+}
+
+// Test case for relaying outbox messages in order per aggregate
+func TestOutboxRelay(t *testing.T) {
+	logger.Init()
+
+	store := &memoryOutboxStore{
+		messages: []outbox.Message{
+			{ID: "1", Sequence: 1, AggregateType: entities.AggregateOrder, AggregateID: "a", EventType: entities.EventOrderCreated},
+			{ID: "2", Sequence: 2, AggregateType: entities.AggregateOrder, AggregateID: "b", EventType: entities.EventOrderCreated},
+			{ID: "3", Sequence: 3, AggregateType: entities.AggregateOrder, AggregateID: "a", EventType: entities.EventOrderStatusChanged},
+			{ID: "4", Sequence: 4, AggregateType: entities.AggregateOrder, AggregateID: "b", EventType: entities.EventOrderStatusChanged},
+		},
+		published: map[string]bool{},
+		retryAt:   map[string]time.Time{},
+	}
+	publisher := &flakyPublisher{failAggregate: "a"}
+	relay := outbox.NewRelay(store, publisher, config.Outbox{BatchSize: 10, Timeout: time.Second, RetryBaseDelay: time.Millisecond, RetryMaxDelay: time.Second})
+
+	// Aggregate a waits for its failed message, aggregate b goes on
+	assert.NoError(t, relay.RelayDue(context.Background()))
+	assert.Equal(t, []string{"2", "4"}, publishedIDs(publisher.Messages()))
+	assert.Equal(t, 1, store.messages[0].Attempts)
+
+	time.Sleep(5 * time.Millisecond)
+	assert.NoError(t, relay.RelayDue(context.Background()))
+	assert.Equal(t, []string{"2", "4", "1", "3"}, publishedIDs(publisher.Messages()))
+}
+
+// Test case for two relays sharing an outbox while a batch takes longer to
+// publish than a couple of publish timeouts
+func TestOutboxRelaysShareOutbox(t *testing.T) {
+	logger.Init()
+
+	store := newLeasingOutboxStore(
+		outbox.Message{ID: "1", Sequence: 1, AggregateType: entities.AggregateOrder, AggregateID: "a", EventType: entities.EventOrderCreated},
+		outbox.Message{ID: "2", Sequence: 2, AggregateType: entities.AggregateOrder, AggregateID: "a", EventType: entities.EventOrderStatusChanged},
+		outbox.Message{ID: "3", Sequence: 3, AggregateType: entities.AggregateOrder, AggregateID: "a", EventType: entities.EventOrderStatusChanged},
+		outbox.Message{ID: "4", Sequence: 4, AggregateType: entities.AggregateOrder, AggregateID: "a", EventType: entities.EventOrderStatusChanged},
+	)
+	publisher := &slowPublisher{delay: 20 * time.Millisecond}
+	conf := config.Outbox{BatchSize: 4, Timeout: 30 * time.Millisecond, RetryBaseDelay: time.Millisecond, RetryMaxDelay: time.Second}
+	first := outbox.NewRelay(store, publisher, conf)
+	second := outbox.NewRelay(store, publisher, conf)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, first.RelayDue(context.Background()))
+	}()
+
+	// The second relay keeps polling while the first publishes its batch
+	for polling := true; polling; {
+		select {
+		case <-done:
+			polling = false
+		case <-time.After(5 * time.Millisecond):
+			assert.NoError(t, second.RelayDue(context.Background()))
+		}
+	}
+	assert.NoError(t, second.RelayDue(context.Background()))
+
+	assert.Equal(t, []string{"1", "2", "3", "4"}, publisher.received)
+}
+
+// Test case for posting outbox messages to an HTTP consumer
+func TestOutboxHTTPPublisher(t *testing.T) {
+	var received map[string]interface{}
+	status := http.StatusInternalServerError
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer consumer.Close()
+
+	publisher, err := outbox.NewPublisher(config.Outbox{Publisher: "http", URL: consumer.URL})
+	assert.NoError(t, err)
+
+	message := outbox.Message{ID: "1", Sequence: 7, AggregateType: entities.AggregateCustomer, AggregateID: "c", EventType: entities.EventCustomerCreated, Payload: []byte(`{"name":"Jane"}`)}
+	assert.Error(t, publisher.Publish(context.Background(), message))
+
+	status = http.StatusAccepted
+	assert.NoError(t, publisher.Publish(context.Background(), message))
+	assert.Equal(t, entities.EventCustomerCreated, received["type"])
+	assert.Equal(t, float64(7), received["sequence"])
+	assert.Equal(t, map[string]interface{}{"name": "Jane"}, received["data"])
+
+	_, err = outbox.NewPublisher(config.Outbox{Publisher: "kafka"})
+	assert.ErrorIs(t, err, outbox.ErrUnknownPublisher)
+}
+
+// Test case for claiming outbox events from postgres in order, one batch per
+// relay even when relays claim at the same time
+func TestOutboxClaimMessages(t *testing.T) {
+	logger.Init()
+
+	db := newPostgresSchema(t, "outbox_events")
+	store := repository.NewOutboxRepository(db)
+	now := time.Now()
+
+	appendEvents := func(aggregateID uuid.UUID, count int) []string {
+		var ids []string
+		for i := 0; i < count; i++ {
+			event := entities.OutboxEvent{ID: uuid.New(), CreatedAt: now, AggregateType: entities.AggregateOrder, AggregateID: aggregateID, EventType: entities.EventOrderStatusChanged, Payload: []byte(`{}`), NextAttemptAt: now}
+			if err := db.GetDb().Create(&event).Error; err != nil {
+				t.Fatalf("Failed to append event: %v", err)
+			}
+			ids = append(ids, event.ID.String())
+		}
+		return ids
+	}
+
+	first := appendEvents(uuid.New(), 3)
+	second := appendEvents(uuid.New(), 1)
+
+	// Oldest first, up to the limit
+	claimed, err := store.ClaimMessages(now, 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, first[:2], publishedIDs(claimed))
+
+	// The rest of the first aggregate waits behind its leased events
+	claimed, err = store.ClaimMessages(now, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, second, publishedIDs(claimed))
+
+	for _, id := range first[:2] {
+		assert.NoError(t, store.MarkPublished(outbox.Message{ID: id}, now))
+	}
+	claimed, err = store.ClaimMessages(now, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, first[2:], publishedIDs(claimed))
+
+	for _, id := range append(first[2:], second...) {
+		assert.NoError(t, store.MarkPublished(outbox.Message{ID: id}, now))
+	}
+
+	// Relays claiming at the same time get one batch of the aggregate
+	// between them, in order
+	later := now.Add(time.Hour)
+	contended := appendEvents(uuid.New(), 10)
+
+	batches := make([][]string, 4)
+	var wg sync.WaitGroup
+	for i := range batches {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			claimed, err := store.ClaimMessages(later, 5, time.Minute)
+			assert.NoError(t, err)
+			batches[i] = publishedIDs(claimed)
+		}(i)
+	}
+	wg.Wait()
+
+	var claimedIDs []string
+	for _, batch := range batches {
+		if len(batch) > 0 {
+			assert.Empty(t, claimedIDs, "two relays claimed events of the same aggregate")
+			claimedIDs = batch
+		}
+	}
+	assert.Equal(t, contended[:5], claimedIDs)
+}
+
+func publishedIDs(messages []outbox.Message) []string {
+	ids := []string{}
+	for _, message := range messages {
+		ids = append(ids, message.ID)
+	}
+	return ids
+}
@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for the fake payment provider
+func TestFakePaymentProvider(t *testing.T) {
+	provider, err := payment.NewRegistry(payment.NewFakeProvider()).Get("FAKE")
+	if err != nil {
+		t.Fatalf("Fake provider not registered: %v", err)
+	}
+
+	_, err = provider.Authorize(payment.AuthorizeRequest{Amount: money.New(1000, "INR"), PaymentMethod: payment.FakeDeclinedMethod})
+	assert.True(t, errors.Is(err, payment.ErrDeclined))
+
+	authorization, err := provider.Authorize(payment.AuthorizeRequest{Amount: money.New(1000, "INR"), PaymentMethod: "tok_visa"})
+	assert.NoError(t, err)
+
+	// Nothing can be refunded before the capture
+	_, err = provider.Refund(authorization.Reference, money.New(1, "INR"))
+	assert.Error(t, err)
+
+	_, err = provider.Capture(authorization.Reference, money.New(1000, "INR"))
+	assert.NoError(t, err)
+
+	_, err = provider.Void(authorization.Reference)
+	assert.Error(t, err)
+
+	_, err = provider.Refund(authorization.Reference, money.New(600, "INR"))
+	assert.NoError(t, err)
+	_, err = provider.Refund(authorization.Reference, money.New(401, "INR"))
+	assert.Error(t, err)
+	_, err = provider.Refund(authorization.Reference, money.New(400, "INR"))
+	assert.NoError(t, err)
+
+	_, err = payment.NewRegistry().Get("fake")
+	assert.True(t, errors.Is(err, payment.ErrUnknownProvider))
+}
+
+// Test case for paying for an order and refunding it
+func TestOrderPayment(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Paying Customer",
+		Email: "payment." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressID := createShippingAddress(t, customer.ID.String())
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: addressID,
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var order entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	pay := func(method string) *http.Response {
+		paymentPayloadJSON, _ := json.Marshal(entities.PaymentRequest{PaymentMethod: method})
+		resp, err := http.Post("http://localhost:8080/api/orders/"+order.ID.String()+"/payments", "application/json", bytes.NewBuffer(paymentPayloadJSON))
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	// A declined payment leaves the order pending
+	declined := pay(payment.FakeDeclinedMethod)
+	defer declined.Body.Close()
+	assert.Equal(t, http.StatusPaymentRequired, declined.StatusCode)
+
+	captured := pay("tok_visa")
+	defer captured.Body.Close()
+	assert.Equal(t, http.StatusCreated, captured.StatusCode)
+
+	var paid entities.Payment
+	if err := json.NewDecoder(captured.Body).Decode(&paid); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.PaymentCaptured, paid.Status)
+	assert.Equal(t, order.TotalPrice, paid.Captured)
+
+	// The order is paid only once
+	again := pay("tok_visa")
+	defer again.Body.Close()
+	assert.Equal(t, http.StatusConflict, again.StatusCode)
+
+	resp, err = http.Get("http://localhost:8080/api/orders/" + order.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var fetched entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&fetched); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.Paid, fetched.Status)
+
+	amount := int64(1)
+	refundPayloadJSON, _ := json.Marshal(entities.RefundRequest{Amount: &amount, Reason: "Damaged packaging"})
+	resp, err = http.Post("http://localhost:8080/api/orders/"+order.ID.String()+"/payments/"+paid.ID.String()+"/refunds", "application/json", bytes.NewBuffer(refundPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var refunded entities.Payment
+	if err := json.NewDecoder(resp.Body).Decode(&refunded); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.PaymentPartiallyRefunded, refunded.Status)
+	assert.Equal(t, money.New(1, order.TotalPrice.Currency), refunded.Refunded)
+	assert.Len(t, refunded.Refunds, 1)
+}
+
+// Test case for cancelling a paid order, which refunds its payment
+func TestCancelPaidOrder(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Refunded Customer",
+		Email: "cancel.paid." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressID := createShippingAddress(t, customer.ID.String())
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: addressID,
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var order entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	paymentPayloadJSON, _ := json.Marshal(entities.PaymentRequest{PaymentMethod: "tok_visa"})
+	resp, err = http.Post("http://localhost:8080/api/orders/"+order.ID.String()+"/payments", "application/json", bytes.NewBuffer(paymentPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var paid entities.Payment
+	if err := json.NewDecoder(resp.Body).Decode(&paid); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.PaymentCaptured, paid.Status)
+
+	cancelPayloadJSON, _ := json.Marshal(entities.CancelOrderRequest{
+		Reason:      entities.ReasonCustomerRequest,
+		CancelledBy: entities.CancelledByCustomer,
+	})
+	resp, err = http.Post("http://localhost:8080/api/orders/"+order.ID.String()+"/cancel", "application/json", bytes.NewBuffer(cancelPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var cancelled entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&cancelled); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.Cancelled, cancelled.Status)
+
+	resp, err = http.Get("http://localhost:8080/api/orders/" + order.ID.String() + "/payments")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var payments []entities.Payment
+	if err := json.NewDecoder(resp.Body).Decode(&payments); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if assert.Len(t, payments, 1) {
+		// The whole capture goes back to the customer
+		assert.Equal(t, entities.PaymentRefunded, payments[0].Status)
+		assert.Equal(t, paid.Captured, payments[0].Refunded)
+		assert.Len(t, payments[0].Refunds, 1)
+	}
+}
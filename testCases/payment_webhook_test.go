@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/handler"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const fakeWebhookSecret = "whsec_local_fake" // Replace with payments.webhooksecrets.fake of the server
+
+// Test case for verifying and parsing payment webhooks
+func TestPaymentWebhookVerification(t *testing.T) {
+	verifier := payment.NewWebhookVerifier(map[string]string{"Fake": "secret"}, 5*time.Minute)
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","payment_reference":"fake_auth_1"}`)
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := hex.EncodeToString(payment.Sign("secret", timestamp, payload))
+
+	assert.True(t, verifier.Accepts("fake"))
+	assert.False(t, verifier.Accepts("other"))
+	assert.NoError(t, verifier.Verify("fake", timestamp, signature, payload, now))
+
+	// Tampered payloads, other secrets and stale signatures are rejected
+	assert.True(t, errors.Is(verifier.Verify("fake", timestamp, signature, append(payload, ' '), now), payment.ErrInvalidSignature))
+	assert.True(t, errors.Is(verifier.Verify("fake", timestamp, hex.EncodeToString(payment.Sign("other", timestamp, payload)), payload, now), payment.ErrInvalidSignature))
+	assert.True(t, errors.Is(verifier.Verify("fake", timestamp, signature, payload, now.Add(6*time.Minute)), payment.ErrInvalidSignature))
+
+	// A tolerance of 0 falls back to the default instead of accepting any age
+	unbounded := payment.NewWebhookVerifier(map[string]string{"Fake": "secret"}, 0)
+	assert.NoError(t, unbounded.Verify("fake", timestamp, signature, payload, now.Add(payment.DefaultWebhookTolerance)))
+	assert.True(t, errors.Is(unbounded.Verify("fake", timestamp, signature, payload, now.Add(24*time.Hour)), payment.ErrInvalidSignature))
+
+	event, err := payment.ParseEvent(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, payment.EventCaptured, event.Type)
+
+	_, err = payment.ParseEvent([]byte(`{"id":"evt_2","type":"payment.refunded","payment_reference":"fake_auth_1"}`))
+	assert.True(t, errors.Is(err, payment.ErrInvalidEvent))
+}
+
+// Test case for rejecting webhooks too large to verify
+func TestPaymentWebhookTooLarge(t *testing.T) {
+	logger.Init()
+
+	app := echo.New()
+	paymentHandler := handler.NewPaymentHandler(nil, payment.NewWebhookVerifier(map[string]string{"fake": "secret"}, time.Minute))
+	app.POST("/webhooks/payments/:provider", paymentHandler.ReceivePaymentWebhook)
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/webhooks/payments/fake", bytes.NewReader(make([]byte, 2<<20))))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+// Test case for a provider capturing a payment through a webhook
+func TestPaymentWebhookCapture(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Webhook Customer",
+		Email: "webhook." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressID := createShippingAddress(t, customer.ID.String())
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: addressID,
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var order entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	paymentPayloadJSON, _ := json.Marshal(entities.PaymentRequest{PaymentMethod: "tok_visa", AuthorizeOnly: true})
+	resp, err = http.Post("http://localhost:8080/api/orders/"+order.ID.String()+"/payments", "application/json", bytes.NewBuffer(paymentPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var authorized entities.Payment
+	if err := json.NewDecoder(resp.Body).Decode(&authorized); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.PaymentAuthorized, authorized.Status)
+
+	payload, _ := json.Marshal(payment.Event{ID: "evt_" + uuid.NewString(), Type: payment.EventCaptured, PaymentReference: authorized.ProviderReference})
+	sendWebhook := func(secret string) *http.Response {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req, _ := http.NewRequest(http.MethodPost, "http://localhost:8080/webhooks/payments/fake", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(payment.TimestampHeader, timestamp)
+		req.Header.Set(payment.SignatureHeader, hex.EncodeToString(payment.Sign(secret, timestamp, payload)))
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		return resp
+	}
+
+	forged := sendWebhook("not-the-secret")
+	defer forged.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, forged.StatusCode)
+
+	first := sendWebhook(fakeWebhookSecret)
+	defer first.Body.Close()
+	assert.Equal(t, http.StatusOK, first.StatusCode)
+
+	var record entities.PaymentWebhookEvent
+	if err := json.NewDecoder(first.Body).Decode(&record); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.WebhookProcessed, record.Status)
+	if assert.NotNil(t, record.OrderID) {
+		assert.Equal(t, order.ID, *record.OrderID)
+	}
+
+	// Providers resend events, they are only applied once
+	resent := sendWebhook(fakeWebhookSecret)
+	defer resent.Body.Close()
+	assert.Equal(t, http.StatusOK, resent.StatusCode)
+	assert.Equal(t, "true", resent.Header.Get("Webhook-Duplicate"))
+
+	resp, err = http.Get("http://localhost:8080/api/orders/" + order.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var paid entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&paid); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, entities.Paid, paid.Status)
+}
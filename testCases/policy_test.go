@@ -0,0 +1,49 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for resolving the order policy from defaults, countries and overrides
+func TestOrderPolicy(t *testing.T) {
+	one, three, unlimited := 1, 3, 0
+	engine := policy.NewEngine(&config.OrderPolicy{
+		OrderLimits: config.OrderLimits{MaxOpenOrders: &one, MaxOrderValue: map[string]int64{"inr": 100000}},
+		Countries: map[string]config.OrderLimits{
+			"india": {MaxOpenOrders: &three},
+		},
+	})
+
+	// Default limits
+	defaults := engine.For("Germany", nil)
+	assert.Nil(t, defaults.CheckOpenOrders(0))
+	if violation := defaults.CheckOpenOrders(1); assert.NotNil(t, violation) {
+		assert.Equal(t, policy.RuleMaxOpenOrders, violation.Rule)
+		assert.Equal(t, policy.ScopeDefault, violation.Scope)
+	}
+	if violation := defaults.CheckOrderValue(money.New(100001, "INR")); assert.NotNil(t, violation) {
+		assert.Equal(t, policy.RuleMaxOrderValue, violation.Rule)
+	}
+	assert.Nil(t, defaults.CheckOrderValue(money.New(100001, "USD")))
+
+	// The country raises the open order limit but keeps the default order value cap
+	india := engine.For("India", nil)
+	assert.Nil(t, india.CheckOpenOrders(2))
+	if violation := india.CheckOpenOrders(3); assert.NotNil(t, violation) {
+		assert.Equal(t, "country India", violation.Scope)
+	}
+	assert.NotNil(t, india.CheckOrderValue(money.New(100001, "INR")))
+
+	// The customer's override wins and zero lifts the limit
+	override := engine.For("India", &policy.Limits{MaxOpenOrders: &unlimited, MaxOrderValue: map[string]int64{"INR": 500}})
+	assert.Nil(t, override.CheckOpenOrders(10))
+	if violation := override.CheckOrderValue(money.New(501, "INR")); assert.NotNil(t, violation) {
+		assert.Equal(t, policy.ScopeCustomer, violation.Scope)
+		assert.Contains(t, violation.Error(), "max_order_value")
+	}
+}
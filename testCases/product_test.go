@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for the product create, fetch and delete endpoints
+func TestProductLifecycle(t *testing.T) {
+	price := money.New(1250, "INR")
+	productPayload := entities.ProductRequest{
+		Name:     "Notebook",
+		Category: "Stationery",
+		Price:    &price,
+	}
+
+	productPayloadJSON, _ := json.Marshal(productPayload)
+
+	// Send POST request to create product
+	resp, err := http.Post("http://localhost:8080/api/products", "application/json", bytes.NewBuffer(productPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var product entities.Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, "Notebook", product.Name)
+
+	// Send DELETE request to soft delete the product
+	req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/api/products/"+product.ID.String(), nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Deleted products are no longer served by the catalog
+	resp, err = http.Get("http://localhost:8080/api/products/" + product.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// Test case for CreateProduct rejecting a negative price
+func TestCreateProductNegativePrice(t *testing.T) {
+	price := money.New(-100, "INR")
+	productPayloadJSON, _ := json.Marshal(entities.ProductRequest{
+		Name:     "Broken",
+		Category: "Stationery",
+		Price:    &price,
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/products", "application/json", bytes.NewBuffer(productPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// Test case for the timestamps maintained on every entity
+func TestProductTimestamps(t *testing.T) {
+	price := money.New(500, "INR")
+	productPayloadJSON, _ := json.Marshal(entities.ProductRequest{
+		Name:     "Pencil",
+		Category: "Stationery",
+		Price:    &price,
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/products", "application/json", bytes.NewBuffer(productPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+
+	var created entities.Product
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// Soft deletion stays internal
+	assert.False(t, created.CreatedAt.IsZero())
+	assert.NotContains(t, fields, "deleted_at")
+
+	// Updating the product moves updated_at but keeps created_at
+	price = money.New(550, "INR")
+	productPayloadJSON, _ = json.Marshal(entities.ProductRequest{
+		Name:     "Pencil",
+		Category: "Stationery",
+		Price:    &price,
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost:8080/api/products/"+created.ID.String(), bytes.NewBuffer(productPayloadJSON))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var updated entities.Product
+	if err := json.NewDecoder(resp.Body).Decode(&updated); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	assert.True(t, created.CreatedAt.Equal(updated.CreatedAt))
+	assert.True(t, updated.UpdatedAt.After(created.UpdatedAt))
+
+	// The product shows up when listing products created since
+	resp, err = http.Get("http://localhost:8080/api/products?sort=-created_at&created_from=" + url.QueryEscape(created.CreatedAt.Format(time.RFC3339Nano)))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var page entities.Page[entities.Product]
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	var ids []string
+	for _, product := range page.Items {
+		ids = append(ids, product.ID.String())
+	}
+	assert.Contains(t, ids, created.ID.String())
+}
+
+// Test case for a price change leaving the prices of existing orders alone
+func TestProductPriceChangeKeepsOrderPrices(t *testing.T) {
+	price := money.New(1000, "INR")
+	productPayloadJSON, _ := json.Marshal(entities.ProductRequest{
+		Name:     "Repriced Pen",
+		Category: "Stationery",
+		Price:    &price,
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/products", "application/json", bytes.NewBuffer(productPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var product entities.Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Repriced Customer",
+		Email: "repriced." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: createShippingAddress(t, customer.ID.String()),
+		Items:             []entities.OrderItemRequest{{ProductID: product.ID.String(), Quantity: 2}},
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var placed entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&placed); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	newPrice := money.New(1500, "INR")
+	productPayloadJSON, _ = json.Marshal(entities.ProductRequest{
+		Name:     "Repriced Pen",
+		Category: "Stationery",
+		Price:    &newPrice,
+	})
+
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost:8080/api/products/"+product.ID.String(), bytes.NewBuffer(productPayloadJSON))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get("http://localhost:8080/api/orders/" + placed.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var order entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// The order keeps the price it was placed at, the product shows the new one
+	if assert.Len(t, order.Items, 1) {
+		assert.Equal(t, price, order.Items[0].UnitPrice)
+		assert.Equal(t, money.New(2000, "INR"), order.Items[0].LineTotal)
+		assert.Equal(t, newPrice, order.Items[0].Product.Price)
+	}
+	assert.Equal(t, placed.Subtotal, order.Subtotal)
+	assert.Equal(t, placed.TotalPrice, order.TotalPrice)
+}
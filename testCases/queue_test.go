@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// leasedQueue is the store fixture of the outbox and webhook tests. It
+// leases claimed items the way the outbox and delivery tables do, so several
+// relays or dispatchers can share it.
+type leasedQueue[T any] struct {
+	mu    sync.Mutex
+	items []T
+	id    func(T) string
+	// waitsFor tells whether item has to wait while the earlier item is
+	// unfinished and leased, nil when items never wait for each other
+	waitsFor    func(earlier, item T) bool
+	finished    map[string]bool
+	leasedUntil map[string]time.Time
+	attempts    map[string]int
+}
+
+func newLeasedQueue[T any](id func(T) string, waitsFor func(earlier, item T) bool, items ...T) *leasedQueue[T] {
+	return &leasedQueue[T]{
+		items:       items,
+		id:          id,
+		waitsFor:    waitsFor,
+		finished:    map[string]bool{},
+		leasedUntil: map[string]time.Time{},
+		attempts:    map[string]int{},
+	}
+}
+
+// claim leases up to limit unfinished items that are not leased yet
+func (q *leasedQueue[T]) claim(now time.Time, limit int, lease time.Duration) []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var claimed []T
+	for i, item := range q.items {
+		if len(claimed) == limit || q.finished[q.id(item)] || q.leasedUntil[q.id(item)].After(now) {
+			continue
+		}
+
+		waiting := false
+		for _, earlier := range q.items[:i] {
+			if q.waitsFor != nil && q.waitsFor(earlier, item) && !q.finished[q.id(earlier)] && q.leasedUntil[q.id(earlier)].After(now) {
+				waiting = true
+			}
+		}
+		if !waiting {
+			claimed = append(claimed, item)
+		}
+	}
+
+	for _, item := range claimed {
+		q.leasedUntil[q.id(item)] = now.Add(lease)
+	}
+	return claimed
+}
+
+// finish records an attempt that leaves the item done
+func (q *leasedQueue[T]) finish(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.attempts[q.id(item)]++
+	q.finished[q.id(item)] = true
+}
+
+// retry records a failed attempt after which the item is due again at retryAt
+func (q *leasedQueue[T]) retry(item T, retryAt time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.attempts[q.id(item)]++
+	q.leasedUntil[q.id(item)] = retryAt
+}
+
+// newPostgresSchema connects to the database of POSTGRES_TEST_DSN, through a
+// schema of its own holding empty copies of the tables, dropped when the test
+// ends. The claims of the test and those of a server running against the
+// same database never see each other's rows.
+func newPostgresSchema(t *testing.T, tables ...string) *gormDatabase {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to connect database: %v", err)
+	}
+
+	schema := "test_" + uuid.NewString()[:8]
+	if err := admin.Exec(fmt.Sprintf("CREATE SCHEMA %q", schema)).Error; err != nil {
+		t.Fatalf("Failed to create schema: %v", err)
+	}
+	for _, table := range tables {
+		if err := admin.Exec(fmt.Sprintf("CREATE TABLE %q.%q (LIKE public.%q INCLUDING ALL)", schema, table, table)).Error; err != nil {
+			t.Fatalf("Failed to create table %v: %v", table, err)
+		}
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema), &gorm.Config{Logger: gormLogger.Discard, TranslateError: true})
+	if err != nil {
+		t.Fatalf("Failed to connect database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDb, err := db.DB(); err == nil {
+			sqlDb.Close()
+		}
+		admin.Exec(fmt.Sprintf("DROP SCHEMA %q CASCADE", schema))
+		if sqlDb, err := admin.DB(); err == nil {
+			sqlDb.Close()
+		}
+	})
+
+	return &gormDatabase{db: db}
+}
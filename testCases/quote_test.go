@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for quoting an order without placing it, reserving stock or
+// redeeming the coupon
+func TestQuoteOrder(t *testing.T) {
+	stock := 1
+	price := money.New(2500, "INR")
+	productPayloadJSON, _ := json.Marshal(entities.ProductRequest{
+		Name:     "Quoted Product",
+		Category: "Quotes",
+		Price:    &price,
+		Stock:    &stock,
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/products", "application/json", bytes.NewBuffer(productPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var product entities.Product
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// The coupon can be redeemed once, so a quote redeeming it would make
+	// the order below fail
+	maxRedemptions := 1
+	couponPayloadJSON, _ := json.Marshal(entities.CouponRequest{
+		Code:           "QUOTE" + strings.ToUpper(uuid.NewString()[:8]),
+		Type:           entities.CouponPercentage,
+		PercentOff:     10,
+		MaxRedemptions: &maxRedemptions,
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/coupons", "application/json", bytes.NewBuffer(couponPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var coupon entities.Coupon
+	if err := json.NewDecoder(resp.Body).Decode(&coupon); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Quoting Customer",
+		Email: "quote." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressID := createShippingAddress(t, customer.ID.String())
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: addressID,
+		Items:             []entities.OrderItemRequest{{ProductID: product.ID.String(), Quantity: 1}},
+		CouponCode:        coupon.Code,
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders/quote", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var quote entities.OrderQuote
+	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Len(t, quote.Items, 1)
+	assert.Equal(t, price, quote.Subtotal)
+	assert.Equal(t, money.New(250, "INR"), quote.DiscountTotal)
+	assert.Empty(t, quote.Violations)
+
+	// Nothing was persisted, the customer still has no orders
+	resp, err = http.Get("http://localhost:8080/api/customers/" + customer.ID.String() + "/orders")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var orders entities.Page[entities.Order]
+	if err := json.NewDecoder(resp.Body).Decode(&orders); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, int64(0), orders.TotalCount)
+
+	// The unit quoted is still in stock
+	resp, err = http.Get("http://localhost:8080/api/products/" + product.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(&product); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, 1, *product.Stock)
+
+	// and the coupon still has its only redemption left
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var order entities.Order
+	if err := json.NewDecoder(resp.Body).Decode(&order); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.Equal(t, quote.DiscountTotal, order.DiscountTotal)
+}
+
+// Test case for taxing an order at the rates of the country it is shipped to
+func TestQuoteOrderTaxedAtDestination(t *testing.T) {
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:    "Abroad Customer",
+		Email:   "abroad." + uuid.NewString() + "@example.com",
+		Country: "India",
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	addressPayloadJSON, _ := json.Marshal(entities.AddressRequest{
+		Recipient:  "Ganesh",
+		Line1:      "1 Unter den Linden",
+		City:       "Berlin",
+		PostalCode: "10117",
+		Country:    "Germany",
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/customers/"+customer.ID.String()+"/addresses", "application/json", bytes.NewBuffer(addressPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var address entities.Address
+	if err := json.NewDecoder(resp.Body).Decode(&address); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: address.ID.String(),
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders/quote", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var quote entities.OrderQuote
+	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	// Germany has no rates configured, although the customer lives in India
+	assert.Equal(t, "international", quote.ShippingZone)
+	assert.Equal(t, int64(0), quote.TaxTotal.Amount)
+	assert.Empty(t, quote.TaxLines)
+}
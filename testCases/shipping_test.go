@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/shipping"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for shipping cost by zone and weight
+func TestShippingCalculation(t *testing.T) {
+	engine := shipping.NewEngine(&config.Shipping{
+		Zones: []config.ShippingZone{
+			{
+				Name:       "domestic",
+				Countries:  []string{"India"},
+				Currency:   "inr",
+				Rates:      []config.ShippingRate{{MaxWeightGrams: 5000, Price: 9900}, {MaxWeightGrams: 1000, Price: 4900}},
+				ExtraPerKg: 2000,
+			},
+			{
+				Name:      "international",
+				Countries: []string{"*"},
+				Currency:  "INR",
+				Rates:     []config.ShippingRate{{MaxWeightGrams: 2000, Price: 149900}},
+			},
+		},
+	})
+
+	testCases := []struct {
+		name    string
+		country string
+		weight  int
+		zone    string
+		cost    money.Money
+	}{
+		{"lightest bracket", "india", 0, "domestic", money.New(4900, "INR")},
+		{"bracket limit", "India", 1000, "domestic", money.New(4900, "INR")},
+		{"heavier bracket", "India", 1001, "domestic", money.New(9900, "INR")},
+		{"started extra kilograms", "India", 6001, "domestic", money.New(13900, "INR")},
+		{"rest of the world", "Germany", 2000, "international", money.New(149900, "INR")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := engine.Calculate(tc.country, tc.weight, "INR")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.zone, result.Zone)
+			assert.Equal(t, tc.cost, result.Cost)
+		})
+	}
+
+	// Zones without extra kilograms refuse orders over their heaviest bracket
+	_, err := engine.Calculate("Germany", 2001, "INR")
+	assert.True(t, errors.Is(err, shipping.ErrNotShippable))
+
+	_, err = engine.Calculate("India", 500, "USD")
+	assert.True(t, errors.Is(err, shipping.ErrNotShippable))
+
+	// Without zones shipping is free
+	result, err := shipping.NewEngine(&config.Shipping{}).Calculate("Germany", 2001, "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, money.Zero("USD"), result.Cost)
+}
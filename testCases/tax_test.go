@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/money"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/tax"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for tax by customer country and product category
+func TestTaxCalculation(t *testing.T) {
+	conf := &config.Tax{
+		Countries: map[string]config.TaxCountry{
+			"india": {Name: "GST", Rate: 18, Categories: map[string]float64{"books": 0, "stationery": 12}},
+		},
+	}
+	lines := []tax.Line{
+		{Category: "Electronics", Amount: money.New(10000, "INR")},
+		{Category: "Stationery", Amount: money.New(2500, "INR")},
+		{Category: "Electronics", Amount: money.New(5000, "INR")},
+		{Category: "Books", Amount: money.New(4000, "INR")},
+	}
+
+	// Tax exclusive prices get tax added per rate, books are exempt
+	results := tax.NewEngine(conf).Calculate("India", lines)
+	assert.Equal(t, []tax.Result{
+		{Rate: tax.Rate{Name: "GST", BasisPoints: 1800}, Taxable: money.New(15000, "INR"), Amount: money.New(2700, "INR")},
+		{Rate: tax.Rate{Name: "GST", BasisPoints: 1200}, Taxable: money.New(2500, "INR"), Amount: money.New(300, "INR")},
+	}, results)
+
+	// Tax inclusive prices already contain the tax
+	conf.PricesIncludeTax = true
+	results = tax.NewEngine(conf).Calculate("INDIA", lines[:1])
+	assert.Equal(t, money.New(1525, "INR"), results[0].Amount)
+
+	// Countries without rates are not taxed
+	assert.Empty(t, tax.NewEngine(conf).Calculate("Germany", lines))
+	assert.Equal(t, "GST 18.00%", results[0].Rate.String())
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/handler"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/shipping"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/tax"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// Test case for answering requests that outlive their deadline with 504
+func TestRequestTimeout(t *testing.T) {
+	logger.Init()
+
+	app := echo.New()
+	app.Use(handler.RequestTimeout(time.Second, map[string]time.Duration{"GET /slow/:id": 10 * time.Millisecond}))
+
+	// Waits for its deadline like a query that takes too long
+	slow := func(c echo.Context) error {
+		<-c.Request().Context().Done()
+		return c.JSON(http.StatusInternalServerError, c.Request().Context().Err().Error())
+	}
+	app.GET("/slow/:id", slow)
+	app.GET("/fast", func(c echo.Context) error {
+		_, ok := c.Request().Context().Deadline()
+		assert.True(t, ok)
+		return c.JSON(http.StatusOK, "Ok")
+	})
+
+	rec := httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow/1", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+
+	rec = httptest.NewRecorder()
+	app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+// Test case for cancelling repository queries with the request context
+func TestRepositoryContextCancelled(t *testing.T) {
+	logger.Init()
+
+	db, tracker := newTrackedDatabase(t)
+	repo := repository.NewCustomerRepository(db, policy.NewEngine(&config.OrderPolicy{}), tax.NewEngine(&config.Tax{}), shipping.NewEngine(&config.Shipping{}), payment.NewRegistry(payment.NewFakeProvider()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs := repo.GetCustomerByID(ctx, "11ac5f2d-18ea-46ad-9cca-3f36c84ce123")
+	assert.NotNil(t, errs)
+
+	_, errs = repo.GetOrderByID(ctx, "11ac5f2d-18ea-46ad-9cca-3f36c84ce123")
+	assert.NotNil(t, errs)
+
+	assert.Empty(t, tracker.statements)
+	assert.Equal(t, 0, tracker.open)
+}
@@ -0,0 +1,260 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/database"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/payment"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/policy"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/shipping"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/tax"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// txTracker is a database/sql driver that runs no SQL but keeps count of
+// transactions and the statements sent, failing every query when told to
+type txTracker struct {
+	mu         sync.Mutex
+	open       int
+	commits    int
+	rollbacks  int
+	statements []string
+	fail       error
+}
+
+func (t *txTracker) Open(name string) (driver.Conn, error) { return &trackedConn{t}, nil }
+
+func (t *txTracker) record(query string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.statements = append(t.statements, query)
+	return t.fail
+}
+
+func (t *txTracker) end(commit bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.open--
+	if commit {
+		t.commits++
+	} else {
+		t.rollbacks++
+	}
+	return nil
+}
+
+type trackedConn struct{ t *txTracker }
+
+func (c *trackedConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *trackedConn) Close() error { return nil }
+
+func (c *trackedConn) Ping(ctx context.Context) error {
+	c.t.mu.Lock()
+	defer c.t.mu.Unlock()
+
+	return c.t.fail
+}
+
+func (c *trackedConn) Begin() (driver.Tx, error) {
+	c.t.mu.Lock()
+	defer c.t.mu.Unlock()
+
+	c.t.open++
+	return &trackedTx{c.t}, nil
+}
+
+func (c *trackedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.t.record(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *trackedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.t.record(query); err != nil {
+		return nil, err
+	}
+	return emptyRows{}, nil
+}
+
+type trackedTx struct{ t *txTracker }
+
+func (tx *trackedTx) Commit() error   { return tx.t.end(true) }
+func (tx *trackedTx) Rollback() error { return tx.t.end(false) }
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return nil }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+// gormDatabase is a database.Database on a gorm connection, to a txTracker
+// or to a test schema of postgres
+type gormDatabase struct{ db *gorm.DB }
+
+func newTrackedDatabase(t *testing.T) (*gormDatabase, *txTracker) {
+	tracker := &txTracker{}
+	sql.Register(t.Name(), tracker)
+
+	sqlDb, err := sql.Open(t.Name(), "")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDb}), &gorm.Config{Logger: gormLogger.Discard})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	return &gormDatabase{db: db}, tracker
+}
+
+func (d *gormDatabase) GetDb() *gorm.DB                                      { return d.db }
+func (d *gormDatabase) CloseDb(db *gorm.DB) error                            { return nil }
+func (d *gormDatabase) MigrateUp() error                                     { return nil }
+func (d *gormDatabase) MigrateDown(steps int) error                          { return nil }
+func (d *gormDatabase) MigrationStatus() ([]database.MigrationStatus, error) { return nil, nil }
+
+func (d *gormDatabase) Ping(ctx context.Context) error {
+	sqlDb, err := d.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDb.PingContext(ctx)
+}
+
+func (d *gormDatabase) Stats() sql.DBStats {
+	sqlDb, _ := d.db.DB()
+	return sqlDb.Stats()
+}
+
+func (d *gormDatabase) WithTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return database.WithTx(ctx, d.db, fn)
+}
+
+// Test case for committing and rolling back units of work
+func TestWithTx(t *testing.T) {
+	db, tracker := newTrackedDatabase(t)
+	errBoom := errors.New("boom")
+
+	assert.NoError(t, db.WithTx(context.Background(), func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE products SET stock = 1").Error
+	}))
+	assert.Equal(t, 1, tracker.commits)
+
+	assert.ErrorIs(t, db.WithTx(context.Background(), func(tx *gorm.DB) error {
+		return errBoom
+	}), errBoom)
+	assert.Equal(t, 1, tracker.rollbacks)
+
+	assert.Panics(t, func() {
+		_ = db.WithTx(context.Background(), func(tx *gorm.DB) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 2, tracker.rollbacks)
+	assert.Equal(t, 0, tracker.open)
+}
+
+// Test case for nesting units of work in savepoints
+func TestWithTxNested(t *testing.T) {
+	db, tracker := newTrackedDatabase(t)
+	errBoom := errors.New("boom")
+
+	err := db.WithTx(context.Background(), func(tx *gorm.DB) error {
+		inner := db.WithTx(tx.Statement.Context, func(tx *gorm.DB) error {
+			return errBoom
+		})
+		assert.ErrorIs(t, inner, errBoom)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	// Only the savepoint is rolled back, the outer transaction commits
+	assert.Equal(t, 1, tracker.commits)
+	assert.Equal(t, 0, tracker.rollbacks)
+	assert.Equal(t, 0, tracker.open)
+	if assert.Len(t, tracker.statements, 2) {
+		assert.Contains(t, tracker.statements[0], "SAVEPOINT")
+		assert.Contains(t, tracker.statements[1], "ROLLBACK TO SAVEPOINT")
+	}
+}
+
+// Test case for leaving no transaction open when repository methods fail
+func TestRepositoryTransactionsOnError(t *testing.T) {
+	logger.Init()
+
+	db, tracker := newTrackedDatabase(t)
+	tracker.fail = errors.New("connection reset")
+
+	repo := repository.NewCustomerRepository(db, policy.NewEngine(&config.OrderPolicy{}), tax.NewEngine(&config.Tax{}), shipping.NewEngine(&config.Shipping{}), payment.NewRegistry(payment.NewFakeProvider()))
+	id := "11ac5f2d-18ea-46ad-9cca-3f36c84ce123"
+
+	_, errs := repo.CreateCustomer(context.Background(), &entities.Customer{Name: "Jane", Email: "jane@example.com"})
+	assert.NotNil(t, errs)
+
+	assert.NotNil(t, repo.DeleteCustomer(context.Background(), id))
+
+	_, errs = repo.CreateOrder(context.Background(), entities.OrderRequest{CustomerID: id, Items: []entities.OrderItemRequest{{ProductID: id, Quantity: 1}}})
+	assert.NotNil(t, errs)
+
+	_, errs = repo.QuoteOrder(context.Background(), entities.OrderRequest{CustomerID: id, Items: []entities.OrderItemRequest{{ProductID: id, Quantity: 1}}})
+	assert.NotNil(t, errs)
+
+	_, errs = repo.GetOrderByID(context.Background(), id)
+	assert.NotNil(t, errs)
+
+	_, errs = repo.UpdateOrderStatus(context.Background(), id, entities.Shipped)
+	assert.NotNil(t, errs)
+
+	// Not found: the queries succeed but return no rows
+	tracker.fail = nil
+	_, errs = repo.GetOrderByID(context.Background(), id)
+	assert.Equal(t, 404, errs.HttpStatusCode())
+
+	assert.Equal(t, 7, tracker.rollbacks)
+	assert.Equal(t, 0, tracker.commits)
+	assert.Equal(t, 0, tracker.open)
+}
+
+// Test case for quotes reading the customer without the lock orders take
+func TestQuoteOrderTakesNoLocks(t *testing.T) {
+	logger.Init()
+
+	db, tracker := newTrackedDatabase(t)
+	repo := repository.NewCustomerRepository(db, policy.NewEngine(&config.OrderPolicy{}), tax.NewEngine(&config.Tax{}), shipping.NewEngine(&config.Shipping{}), payment.NewRegistry(payment.NewFakeProvider()))
+	id := "11ac5f2d-18ea-46ad-9cca-3f36c84ce123"
+	request := entities.OrderRequest{CustomerID: id, Items: []entities.OrderItemRequest{{ProductID: id, Quantity: 1}}}
+
+	// The customer does not exist in the empty database, so both stop there
+	_, errs := repo.QuoteOrder(context.Background(), request)
+	assert.Equal(t, 404, errs.HttpStatusCode())
+	if assert.Len(t, tracker.statements, 1) {
+		assert.NotContains(t, tracker.statements[0], "FOR UPDATE")
+	}
+
+	tracker.statements = nil
+	_, errs = repo.CreateOrder(context.Background(), request)
+	assert.Equal(t, 404, errs.HttpStatusCode())
+	if assert.Len(t, tracker.statements, 1) {
+		assert.Contains(t, tracker.statements[0], "FOR UPDATE")
+	}
+}
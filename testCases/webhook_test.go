@@ -0,0 +1,414 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/config"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/entities"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/logger"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/pkg/webhook"
+	"github.com/ganyacc/Ganesh_OrderProcessingSystem/repository"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryWebhookStore is a delivery queue kept in memory
+type memoryWebhookStore struct {
+	mu         sync.Mutex
+	deliveries []webhook.Delivery
+	attempts   []webhook.Attempt
+}
+
+func (m *memoryWebhookStore) ClaimDeliveries(now time.Time, limit int, lease time.Duration) ([]webhook.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	claimed := m.deliveries
+	m.deliveries = nil
+	return claimed, nil
+}
+
+func (m *memoryWebhookStore) RecordAttempt(delivery webhook.Delivery, attempt webhook.Attempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attempts = append(m.attempts, attempt)
+	if attempt.Outcome == webhook.Retry {
+		delivery.Attempts++
+		m.deliveries = append(m.deliveries, delivery)
+	}
+	return nil
+}
+
+// leasingWebhookStore leases claimed deliveries the way the delivery table
+// does
+type leasingWebhookStore struct {
+	*leasedQueue[webhook.Delivery]
+}
+
+func newLeasingWebhookStore(deliveries ...webhook.Delivery) leasingWebhookStore {
+	return leasingWebhookStore{newLeasedQueue(func(delivery webhook.Delivery) string { return delivery.ID }, nil, deliveries...)}
+}
+
+func (s leasingWebhookStore) ClaimDeliveries(now time.Time, limit int, lease time.Duration) ([]webhook.Delivery, error) {
+	return s.claim(now, limit, lease), nil
+}
+
+func (s leasingWebhookStore) RecordAttempt(delivery webhook.Delivery, attempt webhook.Attempt) error {
+	s.finish(delivery)
+	return nil
+}
+
+// Test case for signing, retrying and dead lettering webhook deliveries
+func TestWebhookDispatcher(t *testing.T) {
+	failures := 1
+	var received [][]byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signature := hex.EncodeToString(webhook.Sign("secret", r.Header.Get(webhook.TimestampHeader), body))
+		assert.Equal(t, signature, r.Header.Get(webhook.SignatureHeader))
+		assert.Equal(t, "evt_1", r.Header.Get(webhook.EventIDHeader))
+
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, body)
+	}))
+	defer receiver.Close()
+
+	store := &memoryWebhookStore{deliveries: []webhook.Delivery{
+		{ID: "1", URL: receiver.URL, Secret: "secret", EventID: "evt_1", EventType: entities.EventOrderCreated, Payload: []byte(`{"id":"evt_1"}`)},
+	}}
+	dispatcher := webhook.NewDispatcher(store, config.Webhooks{BatchSize: 10, Timeout: time.Second, MaxAttempts: 3, RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute, AllowPrivateNetworks: true})
+
+	// The first attempt fails and is retried after the base delay
+	assert.NoError(t, dispatcher.DispatchDue())
+	assert.Equal(t, webhook.Retry, store.attempts[0].Outcome)
+	assert.Equal(t, http.StatusServiceUnavailable, store.attempts[0].StatusCode)
+	assert.Equal(t, time.Second, store.attempts[0].NextAttemptAt.Sub(store.attempts[0].AttemptedAt))
+
+	assert.NoError(t, dispatcher.DispatchDue())
+	assert.Equal(t, webhook.Delivered, store.attempts[1].Outcome)
+	assert.Equal(t, [][]byte{[]byte(`{"id":"evt_1"}`)}, received)
+
+	// A subscriber that keeps failing gets the delivery dead lettered
+	store.deliveries = []webhook.Delivery{{ID: "2", URL: receiver.URL + "/missing", Secret: "secret", EventID: "evt_1", Attempts: 2}}
+	failures = 1
+	assert.NoError(t, dispatcher.DispatchDue())
+	assert.Equal(t, webhook.Dead, store.attempts[2].Outcome)
+	assert.Empty(t, store.deliveries)
+
+	assert.Equal(t, time.Second, webhook.Backoff(1, time.Second, time.Minute))
+	assert.Equal(t, 8*time.Second, webhook.Backoff(4, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, webhook.Backoff(20, time.Second, time.Minute))
+}
+
+// Test case for two dispatchers sharing a queue while a batch takes longer
+// to send than a couple of request timeouts
+func TestWebhookDispatchersShareQueue(t *testing.T) {
+	logger.Init()
+
+	var mu sync.Mutex
+	received := map[string]int{}
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		received[r.Header.Get(webhook.EventIDHeader)]++
+	}))
+	defer receiver.Close()
+
+	var deliveries []webhook.Delivery
+	for _, id := range []string{"1", "2", "3", "4"} {
+		deliveries = append(deliveries, webhook.Delivery{ID: id, URL: receiver.URL, Secret: "secret", EventID: "evt_" + id, EventType: entities.EventOrderCreated, Payload: []byte(`{}`)})
+	}
+	store := newLeasingWebhookStore(deliveries...)
+	conf := config.Webhooks{BatchSize: 4, Timeout: 30 * time.Millisecond, MaxAttempts: 3, RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute, AllowPrivateNetworks: true}
+	first := webhook.NewDispatcher(store, conf)
+	second := webhook.NewDispatcher(store, conf)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, first.DispatchDue())
+	}()
+
+	// The second dispatcher keeps polling while the first sends its batch
+	for polling := true; polling; {
+		select {
+		case <-done:
+			polling = false
+		case <-time.After(5 * time.Millisecond):
+			assert.NoError(t, second.DispatchDue())
+		}
+	}
+
+	assert.Equal(t, map[string]int{"evt_1": 1, "evt_2": 1, "evt_3": 1, "evt_4": 1}, received)
+	assert.Equal(t, map[string]int{"1": 1, "2": 1, "3": 1, "4": 1}, store.attempts)
+}
+
+// Test case for refusing subscriber URLs inside the service's network
+func TestWebhookURLGuard(t *testing.T) {
+	guard := webhook.NewURLGuard(false)
+	ctx := context.Background()
+
+	assert.NoError(t, guard.Check(ctx, "https://93.184.216.34/hooks"))
+	assert.Error(t, guard.Check(ctx, "ftp://93.184.216.34/hooks"))
+	assert.Error(t, guard.Check(ctx, "file:///etc/passwd"))
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/api/customers",
+		"http://localhost/hooks",
+		"http://[::1]/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hooks",
+		"http://192.168.1.10/hooks",
+		"http://0.0.0.0/hooks",
+	} {
+		assert.ErrorIs(t, guard.Check(ctx, url), webhook.ErrForbiddenAddress, url)
+	}
+
+	assert.NoError(t, webhook.NewURLGuard(true).Check(ctx, "http://127.0.0.1:8080/hooks"))
+
+	// The dispatcher refuses to connect even when the stored URL was let in
+	called := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	store := &memoryWebhookStore{deliveries: []webhook.Delivery{
+		{ID: "1", URL: receiver.URL, Secret: "secret", EventID: "evt_1", EventType: entities.EventOrderCreated, Payload: []byte(`{}`)},
+	}}
+	dispatcher := webhook.NewDispatcher(store, config.Webhooks{BatchSize: 10, Timeout: time.Second, MaxAttempts: 3, RetryBaseDelay: time.Second, RetryMaxDelay: time.Minute})
+
+	assert.NoError(t, dispatcher.DispatchDue())
+	assert.False(t, called)
+	if assert.Len(t, store.attempts, 1) {
+		assert.Equal(t, webhook.Retry, store.attempts[0].Outcome)
+		assert.Contains(t, store.attempts[0].Error, webhook.ErrForbiddenAddress.Error())
+	}
+}
+
+// Test case for claiming due deliveries from postgres, oldest first and
+// each by one dispatcher even when dispatchers claim at the same time
+func TestWebhookClaimDeliveries(t *testing.T) {
+	logger.Init()
+
+	db := newPostgresSchema(t, "webhook_subscriptions", "webhook_deliveries", "webhook_delivery_attempts")
+	store := repository.NewWebhookRepository(db)
+	now := time.Now()
+
+	subscribe := func(active bool) entities.WebhookSubscription {
+		subscription := entities.WebhookSubscription{URL: "https://example.com/hooks", EventTypes: entities.EventTypeList{entities.EventOrderCreated}, Secret: "secret", Active: active}
+		if err := db.GetDb().Create(&subscription).Error; err != nil {
+			t.Fatalf("Failed to create subscription: %v", err)
+		}
+		return subscription
+	}
+
+	queue := func(subscription entities.WebhookSubscription, count int, due time.Time) []string {
+		var ids []string
+		for i := 0; i < count; i++ {
+			delivery := entities.WebhookDelivery{SubscriptionID: subscription.ID, EventID: uuid.New(), EventType: entities.EventOrderCreated, Payload: []byte(`{}`), Status: entities.DeliveryPending, NextAttemptAt: due.Add(time.Duration(i) * time.Second)}
+			if err := db.GetDb().Create(&delivery).Error; err != nil {
+				t.Fatalf("Failed to queue delivery: %v", err)
+			}
+			ids = append(ids, delivery.ID.String())
+		}
+		return ids
+	}
+
+	active := subscribe(true)
+	due := queue(active, 3, now.Add(-time.Minute))
+	queue(active, 1, now.Add(time.Minute))
+	queue(subscribe(false), 1, now.Add(-time.Hour))
+
+	// Due deliveries of active subscriptions, oldest first, up to the limit
+	claimed, err := store.ClaimDeliveries(now, 2, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, due[:2], deliveryIDs(claimed))
+
+	claimed, err = store.ClaimDeliveries(now, 10, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, due[2:], deliveryIDs(claimed))
+
+	// Dispatchers claiming at the same time never share a delivery
+	later := now.Add(time.Hour)
+	contended := queue(active, 20, later.Add(-time.Minute))
+	for _, id := range due {
+		assert.NoError(t, store.RecordAttempt(webhook.Delivery{ID: id}, webhook.Attempt{Outcome: webhook.Delivered, AttemptedAt: now}))
+	}
+
+	var mu sync.Mutex
+	claims := map[string]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			claimed, err := store.ClaimDeliveries(later, 5, time.Minute)
+			assert.NoError(t, err)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, id := range deliveryIDs(claimed) {
+				claims[id]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Whatever a dispatcher skipped while it was locked is claimed next
+	claimed, err = store.ClaimDeliveries(later, 20, time.Minute)
+	assert.NoError(t, err)
+	for _, id := range deliveryIDs(claimed) {
+		claims[id]++
+	}
+
+	// The delivery not due before the contended ones is claimed too
+	assert.Len(t, claims, len(contended)+1)
+	for _, id := range contended {
+		assert.Equal(t, 1, claims[id], id)
+	}
+}
+
+func deliveryIDs(deliveries []webhook.Delivery) []string {
+	ids := []string{}
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.ID)
+	}
+	return ids
+}
+
+// Test case for storing the event types of a subscription as a JSON array
+func TestWebhookEventTypeList(t *testing.T) {
+	value, err := entities.EventTypeList{entities.EventOrderCreated, entities.EventOrderStatusChanged}.Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `["order.created","order.status_changed"]`, value)
+
+	value, err = entities.EventTypeList(nil).Value()
+	assert.NoError(t, err)
+	assert.Equal(t, `[]`, value)
+
+	var eventTypes entities.EventTypeList
+	assert.NoError(t, eventTypes.Scan([]byte(`["order.created"]`)))
+	assert.Equal(t, entities.EventTypeList{entities.EventOrderCreated}, eventTypes)
+	assert.Error(t, eventTypes.Scan(42))
+}
+
+// Test case for delivering order events to a subscriber. The receiver runs
+// on loopback, so the server needs WEBHOOKS_ALLOWPRIVATENETWORKS=true.
+func TestOrderWebhookDelivery(t *testing.T) {
+	events := make(chan entities.WebhookEvent, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event entities.WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err == nil {
+			events <- event
+		}
+	}))
+	defer receiver.Close()
+
+	subscriptionPayloadJSON, _ := json.Marshal(entities.WebhookSubscriptionRequest{
+		URL:        receiver.URL,
+		EventTypes: []string{entities.EventOrderCreated},
+	})
+
+	resp, err := http.Post("http://localhost:8080/api/webhooks", "application/json", bytes.NewBuffer(subscriptionPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	var subscription entities.CreatedWebhookSubscription
+	if err := json.NewDecoder(resp.Body).Decode(&subscription); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.NotEmpty(t, subscription.Secret)
+
+	defer func() {
+		req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080/api/webhooks/"+subscription.ID.String(), nil)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+
+	// The secret is not shown again once the subscription exists
+	resp, err = http.Get("http://localhost:8080/api/webhooks/" + subscription.ID.String())
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var fetched map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&fetched); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	assert.NotContains(t, fetched, "secret")
+
+	customerPayloadJSON, _ := json.Marshal(entities.CustomerRequest{
+		Name:  "Webhook Subscriber Customer",
+		Email: "subscriber." + uuid.NewString() + "@example.com",
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/customers", "application/json", bytes.NewBuffer(customerPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var customer entities.Customer
+	if err := json.NewDecoder(resp.Body).Decode(&customer); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	orderPayloadJSON, _ := json.Marshal(entities.OrderRequest{
+		CustomerID:        customer.ID.String(),
+		ShippingAddressID: createShippingAddress(t, customer.ID.String()),
+		Items:             []entities.OrderItemRequest{{ProductID: "11ac5f2d-18ea-46ad-9cca-3f36c84ce123", Quantity: 1}}, // Replace with a valid product ID
+	})
+
+	resp, err = http.Post("http://localhost:8080/api/orders", "application/json", bytes.NewBuffer(orderPayloadJSON))
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, entities.EventOrderCreated, event.Type)
+	case <-time.After(30 * time.Second):
+		t.Fatal("Order event was not delivered")
+	}
+
+	resp, err = http.Get("http://localhost:8080/api/webhooks/" + subscription.ID.String() + "/deliveries")
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var deliveries entities.Page[entities.WebhookDelivery]
+	if err := json.NewDecoder(resp.Body).Decode(&deliveries); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if assert.NotEmpty(t, deliveries.Items) {
+		assert.Equal(t, entities.EventOrderCreated, deliveries.Items[0].EventType)
+	}
+}